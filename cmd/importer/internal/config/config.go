@@ -4,11 +4,17 @@ import (
 	"os"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/cetteup/playerpath/internal/domain/provider"
 )
 
 type Config struct {
 	RegistryBaseURL string         `yaml:"registry"`
 	Database        DatabaseConfig `yaml:"db"`
+	// Providers Catalogue of providers to import players from (added to the built-in catalogue, replacing built-in providers with the same ID)
+	Providers []provider.Definition `yaml:"providers"`
+	// Catalogue Built-in providers merged with the ones defined in the config, to be loaded once the config has been applied
+	Catalogue []provider.Definition `yaml:"-"`
 }
 
 type DatabaseConfig struct {
@@ -18,6 +24,7 @@ type DatabaseConfig struct {
 	Password     string `yaml:"passwd"`
 }
 
+// LoadConfig Read the config, validating the providers without loading them (see Config.Catalogue)
 func LoadConfig(path string) (Config, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -30,5 +37,10 @@ func LoadConfig(path string) (Config, error) {
 		return Config{}, err
 	}
 
+	config.Catalogue = provider.Merge(provider.Defaults, config.Providers)
+	if err = provider.Validate(config.Catalogue); err != nil {
+		return Config{}, err
+	}
+
 	return config, nil
 }
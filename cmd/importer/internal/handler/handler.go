@@ -2,11 +2,11 @@ package handler
 
 import (
 	"context"
-	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/cetteup/playerpath/cmd/importer/internal/metrics"
 	"github.com/cetteup/playerpath/internal/domain/player"
 	"github.com/cetteup/playerpath/internal/domain/provider"
 	"github.com/cetteup/playerpath/internal/pkg/registry"
@@ -45,9 +45,11 @@ func (s *Handler) ImportPlayers(ctx context.Context) error {
 	for _, pv := range s.providers {
 		marker, err := s.importPlayers(ctx, pv, s.markers[pv])
 		if err != nil {
+			metrics.ImportFailures.WithLabelValues(pv.String()).Inc()
 			return err
 		}
 		s.markers[pv] = marker
+		metrics.LastImport.WithLabelValues(pv.String()).SetToCurrentTime()
 	}
 
 	return nil
@@ -78,6 +80,7 @@ func (s *Handler) importPlayers(ctx context.Context, pv provider.Provider, marke
 	batch := make([]player.Player, 0, s.batchSize)
 	for p := range players {
 		stats.processed++
+		metrics.PlayersProcessed.WithLabelValues(pv.String()).Inc()
 		batch = append(batch, player.Player{
 			PID:      p.PID,
 			Nick:     p.Nick,
@@ -92,6 +95,7 @@ func (s *Handler) importPlayers(ctx context.Context, pv provider.Provider, marke
 			}
 
 			stats.imported += modified
+			metrics.PlayersImported.WithLabelValues(pv.String()).Add(float64(modified))
 			batch = batch[:0]
 		}
 	}
@@ -108,6 +112,7 @@ func (s *Handler) importPlayers(ctx context.Context, pv provider.Provider, marke
 		}
 
 		stats.imported += modified
+		metrics.PlayersImported.WithLabelValues(pv.String()).Add(float64(modified))
 	}
 
 	log.Info().
@@ -123,7 +128,7 @@ func (s *Handler) load(
 	marker string,
 	out chan<- player.Player,
 ) (string, error) {
-	players, err := s.client.GetPlayers(ctx, registry.WithProviderFilter(strings.ToLower(pv.String())))
+	players, err := s.client.GetPlayers(ctx, registry.WithProviderFilter(provider.GetRegistryName(pv)))
 	if err != nil {
 		return "", err
 	}
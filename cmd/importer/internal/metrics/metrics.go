@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	namespace = "importer"
+)
+
+var (
+	PlayersProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "players_processed_total",
+		Help:      "Number of players loaded from the registry by provider",
+	}, []string{"provider"})
+
+	PlayersImported = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "players_imported_total",
+		Help:      "Number of players inserted or updated in the database by provider",
+	}, []string{"provider"})
+
+	ImportFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "import_failures_total",
+		Help:      "Number of failed imports by provider",
+	}, []string{"provider"})
+
+	LastImport = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_import_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful import by provider",
+	}, []string{"provider"})
+)
@@ -15,6 +15,8 @@ type Options struct {
 
 	Interval  time.Duration
 	BatchSize int
+
+	MetricsAddr string
 }
 
 func Init() *Options {
@@ -26,6 +28,7 @@ func Init() *Options {
 	flag.StringVar(&opts.ConfigPath, "config", "config.yaml", "path to YAML config file")
 	flag.DurationVar(&opts.Interval, "interval", 5*time.Minute, "interval for importing players")
 	flag.IntVar(&opts.BatchSize, "batch", 1000, "number of players to batch-upsert to database")
+	flag.StringVar(&opts.MetricsAddr, "metrics-address", "", "address to serve Prometheus metrics on in format [host]:port (disabled if empty)")
 	flag.Parse()
 	return opts
 }
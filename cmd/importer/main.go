@@ -4,11 +4,13 @@ import (
 	"cmp"
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
@@ -17,6 +19,7 @@ import (
 	"github.com/cetteup/playerpath/cmd/importer/internal/options"
 	"github.com/cetteup/playerpath/internal/domain/player/sql"
 	"github.com/cetteup/playerpath/internal/domain/provider"
+	providersql "github.com/cetteup/playerpath/internal/domain/provider/sql"
 	"github.com/cetteup/playerpath/internal/pkg/registry"
 	"github.com/cetteup/playerpath/internal/sqlutil"
 )
@@ -74,6 +77,17 @@ func main() {
 		}
 	}()
 
+	if err = providersql.NewRepository(db).Sync(ctx, cfg.Catalogue); err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed to sync providers to database")
+	}
+	if err = provider.Load(cfg.Catalogue); err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed to load providers")
+	}
+
 	registryBaseURL := cmp.Or(cfg.RegistryBaseURL, registry.BaseURL)
 	client := registry.NewClient(registryBaseURL, 10*time.Second)
 	repository := sql.NewRepository(db)
@@ -81,16 +95,14 @@ func main() {
 	h := handler.NewHandler(
 		client,
 		repository,
-		[]provider.Provider{
-			provider.BF2Hub,
-			provider.PlayBF2,
-			provider.OpenSpy,
-			provider.B2BF2,
-			provider.Gameppy,
-		},
+		provider.Importable(),
 		opts.BatchSize,
 	)
 
+	if opts.MetricsAddr != "" {
+		go serveMetrics(opts.MetricsAddr)
+	}
+
 	// Trigger import once on startup
 	once := make(chan struct{}, 1)
 	once <- struct{}{}
@@ -112,3 +124,17 @@ func main() {
 		}
 	}
 }
+
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Fatal().
+		Err(srv.ListenAndServe()).
+		Msg("Metrics server stopped")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/config"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/handler"
+)
+
+// serveAdmin Serve the admin API on a separate listener, requiring the configured bearer token for every request
+func serveAdmin(h *handler.Handler, cfg config.AdminConfig, r *reloader) {
+	if cfg.Token == "" {
+		log.Fatal().Msg("Admin API requires a token")
+	}
+
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.Use(middleware.Recover())
+	e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
+		Timeout: time.Second * 10,
+	}))
+	e.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+		LogError:    true,
+		LogRemoteIP: true,
+		LogMethod:   true,
+		LogURI:      true,
+		LogStatus:   true,
+		LogLatency:  true,
+		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			log.Info().
+				Err(v.Error).
+				Str("remote", v.RemoteIP).
+				Str("method", v.Method).
+				Str("URI", v.URI).
+				Int("status", v.Status).
+				Str("latency", v.Latency.Truncate(time.Millisecond).String()).
+				Msg("admin request")
+
+			return nil
+		},
+	}))
+	e.Use(middleware.KeyAuth(func(key string, c echo.Context) (bool, error) {
+		return subtle.ConstantTimeCompare([]byte(key), []byte(cfg.Token)) == 1, nil
+	}))
+
+	players := e.Group("/players")
+	players.GET("/:pid", h.HandleGetPlayer)
+	players.GET("/:pid/override", h.HandleGetOverride)
+	players.PUT("/:pid/override", h.HandleSetOverride)
+	players.DELETE("/:pid/override", h.HandleDeleteOverride)
+	e.GET("/overrides", h.HandleListOverrides)
+	e.GET("/servers", h.HandleListServers)
+	e.GET("/caches", h.HandleGetCaches)
+	e.GET("/breakers", h.HandleGetBreakers)
+	e.POST("/reload", func(c echo.Context) error {
+		if err := r.reload(c.Request().Context()); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to reload config").SetInternal(err)
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	log.Fatal().
+		Err(e.Start(cfg.Address)).
+		Msg("Admin API stopped")
+}
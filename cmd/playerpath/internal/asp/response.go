@@ -1,6 +1,8 @@
 package asp
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -12,13 +14,19 @@ const (
 
 	lineTypeHeader = "H"
 	lineTypeData   = "D"
+	lineTypeSize   = "$"
 
 	delimiter = "\t"
 	linebreak = "\n"
+
+	// ErrorCodeUpstream Error code used for responses substituting invalid upstream responses
+	ErrorCodeUpstream = 999
 )
 
 var (
 	Now = time.Now // mockable for tests
+
+	ErrInvalidResponse = errors.New("invalid ASP response")
 )
 
 type Response struct {
@@ -35,6 +43,59 @@ func NewErrorResponse(code int) *Response {
 	return r.write(responseTypeError, strconv.Itoa(code))
 }
 
+// Parse Parse an ASP response body, validating its structure as well as the size indicated by the last line
+func Parse(body string) (*Response, error) {
+	lines := strings.Split(body, linebreak)
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("%w: missing size line", ErrInvalidResponse)
+	}
+
+	r := &Response{}
+	columns := -1
+	for i, line := range lines[:len(lines)-1] {
+		elems := strings.Split(line, delimiter)
+		switch lineType := elems[0]; {
+		case i == 0 && lineType == responseTypeOK && len(elems) == 1:
+		case i == 0 && lineType == responseTypeError && len(elems) == 2:
+			if _, err := strconv.Atoi(elems[1]); err != nil {
+				return nil, fmt.Errorf("%w: invalid error code: %s", ErrInvalidResponse, elems[1])
+			}
+		case i == 0:
+			return nil, fmt.Errorf("%w: invalid response type line: %q", ErrInvalidResponse, line)
+		case lineType == lineTypeHeader:
+			columns = len(elems) - 1
+		case lineType == lineTypeData:
+			if columns == -1 {
+				return nil, fmt.Errorf("%w: data line %d without preceding header line", ErrInvalidResponse, i)
+			}
+			if len(elems)-1 != columns {
+				return nil, fmt.Errorf("%w: data line %d has %d columns, expected %d", ErrInvalidResponse, i, len(elems)-1, columns)
+			}
+		default:
+			return nil, fmt.Errorf("%w: invalid line type on line %d: %q", ErrInvalidResponse, i, lineType)
+		}
+
+		r.lines = append(r.lines, elems)
+	}
+
+	last := lines[len(lines)-1]
+	elems := strings.Split(last, delimiter)
+	if len(elems) != 3 || elems[0] != lineTypeSize || elems[2] != lineTypeSize {
+		return nil, fmt.Errorf("%w: invalid size line: %q", ErrInvalidResponse, last)
+	}
+
+	size, err := strconv.Atoi(elems[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid size: %s", ErrInvalidResponse, elems[1])
+	}
+
+	if expected := r.size(); size != expected {
+		return nil, fmt.Errorf("%w: size %d does not match content size %d", ErrInvalidResponse, size, expected)
+	}
+
+	return r, nil
+}
+
 func (r *Response) WriteHeader(elems ...string) *Response {
 	return r.write(lineTypeHeader, elems...)
 }
@@ -48,34 +109,158 @@ func (r *Response) write(lineType string, elems ...string) *Response {
 	return r
 }
 
+// OK Whether the response indicates success
+func (r *Response) OK() bool {
+	return len(r.lines) > 0 && r.lines[0][0] == responseTypeOK
+}
+
+// ErrorCode Returns the response's error code, if the response indicates an error
+func (r *Response) ErrorCode() (int, bool) {
+	if len(r.lines) == 0 || r.lines[0][0] != responseTypeError || len(r.lines[0]) < 2 {
+		return 0, false
+	}
+
+	code, err := strconv.Atoi(r.lines[0][1])
+	if err != nil {
+		return 0, false
+	}
+
+	return code, true
+}
+
+// Block A header line along with all data lines following it
+type Block struct {
+	Keys []string
+	Rows [][]string
+}
+
+// Get Returns the value of the given key in the given (zero-based) row
+func (b Block) Get(row int, key string) (string, bool) {
+	if row < 0 || row >= len(b.Rows) {
+		return "", false
+	}
+
+	for i, k := range b.Keys {
+		if k == key {
+			return b.Rows[row][i], true
+		}
+	}
+
+	return "", false
+}
+
+// Records Returns all rows as key/value maps
+func (b Block) Records() []map[string]string {
+	records := make([]map[string]string, 0, len(b.Rows))
+	for _, row := range b.Rows {
+		record := make(map[string]string, len(b.Keys))
+		for i, key := range b.Keys {
+			record[key] = row[i]
+		}
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func (r *Response) Blocks() []Block {
+	blocks := make([]Block, 0)
+	for _, line := range r.lines {
+		switch line[0] {
+		case lineTypeHeader:
+			blocks = append(blocks, Block{Keys: line[1:]})
+		case lineTypeData:
+			if len(blocks) > 0 {
+				b := &blocks[len(blocks)-1]
+				b.Rows = append(b.Rows, line[1:])
+			}
+		}
+	}
+
+	return blocks
+}
+
+// Get Returns the value of the given key in the first data line of the first block containing the key
+func (r *Response) Get(key string) (string, bool) {
+	for _, b := range r.Blocks() {
+		if value, ok := b.Get(0, key); ok {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// Set Sets the value of the given key in every data line of every block containing the key,
+// returning the number of values set
+func (r *Response) Set(key string, value string) int {
+	n := 0
+	column := -1
+	for _, line := range r.lines {
+		switch line[0] {
+		case lineTypeHeader:
+			column = -1
+			for i, k := range line[1:] {
+				if k == key {
+					column = i + 1
+					break
+				}
+			}
+		case lineTypeData:
+			if column != -1 && column < len(line) {
+				line[column] = value
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
 func (r *Response) Serialize() string {
 	var serialized strings.Builder
-	size := 0
 	for _, line := range r.lines {
 		for j, elem := range line {
 			serialized.WriteString(elem)
 			if j+1 < len(line) {
 				serialized.WriteString(delimiter)
 			}
-			// Cannot use len(elem) here, since it counts bytes not characters
-			size += len([]rune(elem))
 		}
 
 		// Still need to append the line indicating the size, so add linebreak for every line
 		serialized.WriteString(linebreak)
 	}
 
-	serialized.WriteString(strings.Join([]string{"$", strconv.Itoa(size), "$"}, delimiter))
+	serialized.WriteString(strings.Join([]string{lineTypeSize, strconv.Itoa(r.size()), lineTypeSize}, delimiter))
 
 	return serialized.String()
 }
 
+func (r *Response) size() int {
+	size := 0
+	for _, line := range r.lines {
+		for _, elem := range line {
+			// Cannot use len(elem) here, since it counts bytes not characters
+			size += len([]rune(elem))
+		}
+	}
+
+	return size
+}
+
 func NewSyntaxErrorResponse() *Response {
 	return NewErrorResponse(107).
 		WriteHeader("asof", "err").
 		WriteData(Timestamp(), "Invalid Syntax!")
 }
 
+// NewUpstreamErrorResponse Error response to use in place of any upstream response that cannot be passed on
+func NewUpstreamErrorResponse() *Response {
+	return NewErrorResponse(ErrorCodeUpstream).
+		WriteHeader("asof", "err").
+		WriteData(Timestamp(), "Upstream Error!")
+}
+
 func Timestamp() string {
 	return strconv.FormatInt(Now().Unix(), 10)
 }
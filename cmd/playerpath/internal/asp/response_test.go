@@ -0,0 +1,130 @@
+package asp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/asp"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		wantOK          bool
+		wantErrorCode   int
+		wantBlocks      []asp.Block
+		wantErrContains string
+	}{
+		{
+			name:   "parses OK response with multiple blocks",
+			body:   "O\nH\tpid\tnick\tasof\nD\t1234567890\twalterwhite\t1771369200\nH\tresult\nD\tOk\n$\t55\t$",
+			wantOK: true,
+			wantBlocks: []asp.Block{
+				{Keys: []string{"pid", "nick", "asof"}, Rows: [][]string{{"1234567890", "walterwhite", "1771369200"}}},
+				{Keys: []string{"result"}, Rows: [][]string{{"Ok"}}},
+			},
+		},
+		{
+			name:   "parses OK response with multiple data lines",
+			body:   "O\nH\tid\tname\nD\t1\ta\nD\t2\tb\n$\t14\t$",
+			wantOK: true,
+			wantBlocks: []asp.Block{
+				{Keys: []string{"id", "name"}, Rows: [][]string{{"1", "a"}, {"2", "b"}}},
+			},
+		},
+		{
+			name:          "parses error response without blocks",
+			body:          "E\t996\n$\t4\t$",
+			wantErrorCode: 996,
+			wantBlocks:    []asp.Block{},
+		},
+		{
+			name:   "counts characters rather than bytes for size",
+			body:   "O\nH\tnick\nD\twältér\n$\t13\t$",
+			wantOK: true,
+			wantBlocks: []asp.Block{
+				{Keys: []string{"nick"}, Rows: [][]string{{"wältér"}}},
+			},
+		},
+		{
+			name:            "fails for HTML body",
+			body:            "<html><body>Bad Gateway</body></html>",
+			wantErrContains: "missing size line",
+		},
+		{
+			name:            "fails for invalid response type",
+			body:            "X\n$\t1\t$",
+			wantErrContains: "invalid response type line",
+		},
+		{
+			name:            "fails for non-numeric error code",
+			body:            "E\tabc\n$\t4\t$",
+			wantErrContains: "invalid error code",
+		},
+		{
+			name:            "fails for data line without header",
+			body:            "O\nD\tOk\n$\t4\t$",
+			wantErrContains: "data line 1 without preceding header line",
+		},
+		{
+			name:            "fails for data line with unexpected number of columns",
+			body:            "O\nH\tpid\tnick\nD\t1234567890\n$\t19\t$",
+			wantErrContains: "data line 2 has 1 columns, expected 2",
+		},
+		{
+			name:            "fails for truncated body",
+			body:            "O\nH\tpid\tnick\nD\t1234567890\twalt",
+			wantErrContains: "invalid size line",
+		},
+		{
+			name:            "fails for size mismatch",
+			body:            "O\nH\tresult\nD\tOk\n$\t42\t$",
+			wantErrContains: "size 42 does not match content size 11",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			r, err := asp.Parse(tt.body)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				assert.ErrorIs(t, err, asp.ErrInvalidResponse)
+				assert.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantOK, r.OK())
+				code, isError := r.ErrorCode()
+				assert.Equal(t, tt.wantErrorCode != 0, isError)
+				assert.Equal(t, tt.wantErrorCode, code)
+				assert.Equal(t, tt.wantBlocks, r.Blocks())
+				assert.Equal(t, tt.body, r.Serialize())
+			}
+		})
+	}
+}
+
+func TestResponse_Set(t *testing.T) {
+	// GIVEN
+	var now = time.Date(2026, 2, 17, 23, 0, 0, 0, time.UTC)
+	asp.Now = func() time.Time { return now }
+	t.Cleanup(func() { asp.Now = time.Now })
+
+	r, err := asp.Parse("O\nH\tasof\nD\t1700000000\nH\tpid\tasof\nD\t1\t1700000000\nD\t2\t1700000000\n$\t49\t$")
+	require.NoError(t, err)
+
+	// WHEN
+	n := r.Set("asof", asp.Timestamp())
+
+	// THEN
+	assert.Equal(t, 3, n)
+	value, ok := r.Get("asof")
+	assert.True(t, ok)
+	assert.Equal(t, "1771369200", value)
+	assert.Equal(t, "O\nH\tasof\nD\t1771369200\nH\tpid\tasof\nD\t1\t1771369200\nD\t2\t1771369200\n$\t49\t$", r.Serialize())
+}
@@ -0,0 +1,112 @@
+//go:generate go tool stringer -type=State -trimprefix=State
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type State int
+
+const (
+	StateClosed   State = 0
+	StateOpen     State = 1
+	StateHalfOpen State = 2
+)
+
+var (
+	Now = time.Now // mockable for tests
+
+	ErrOpen = errors.New("circuit breaker open")
+)
+
+// Breaker Circuit breaker which opens after a number of consecutive failures. Once the cooldown has passed,
+// it allows a single probe request (half-open), closing again if the probe succeeds and re-opening if it fails.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	onChange  func(from, to State)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	opened   time.Time
+	probing  bool
+}
+
+func New(threshold int, cooldown time.Duration, onChange func(from, to State)) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		onChange:  onChange,
+	}
+}
+
+// Allow Determine whether a request may be made. Every allowed request *must* be followed by a call to either
+// Success or Failure, else the breaker may remain half-open indefinitely.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	from := b.state
+	allowed := false
+	switch b.state {
+	case StateClosed:
+		allowed = true
+	case StateOpen:
+		if Now().Sub(b.opened) >= b.cooldown {
+			b.state = StateHalfOpen
+			b.probing = true
+			allowed = true
+		}
+	case StateHalfOpen:
+		// Only allow a single probe at a time
+		if !b.probing {
+			b.probing = true
+			allowed = true
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return allowed
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	from := b.state
+	b.state = StateClosed
+	b.failures = 0
+	b.probing = false
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	from := b.state
+	b.failures++
+	b.probing = false
+	if b.state == StateHalfOpen || (b.state == StateClosed && b.failures >= b.threshold) {
+		b.state = StateOpen
+		b.opened = Now()
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from != to && b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
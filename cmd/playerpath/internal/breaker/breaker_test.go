@@ -0,0 +1,104 @@
+package breaker_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/breaker"
+)
+
+func TestBreaker(t *testing.T) {
+	type step struct {
+		advance     time.Duration
+		allow       bool
+		wantAllowed bool
+		success     bool
+		failure     bool
+		wantState   breaker.State
+	}
+
+	tests := []struct {
+		name        string
+		steps       []step
+		wantChanges []string
+	}{
+		{
+			name: "stays closed below failure threshold",
+			steps: []step{
+				{allow: true, wantAllowed: true, failure: true, wantState: breaker.StateClosed},
+				{allow: true, wantAllowed: true, failure: true, wantState: breaker.StateClosed},
+				{allow: true, wantAllowed: true, success: true, wantState: breaker.StateClosed},
+				{allow: true, wantAllowed: true, failure: true, wantState: breaker.StateClosed},
+			},
+		},
+		{
+			name: "opens after consecutive failures and rejects requests",
+			steps: []step{
+				{allow: true, wantAllowed: true, failure: true, wantState: breaker.StateClosed},
+				{allow: true, wantAllowed: true, failure: true, wantState: breaker.StateClosed},
+				{allow: true, wantAllowed: true, failure: true, wantState: breaker.StateOpen},
+				{advance: 29 * time.Second, allow: true, wantAllowed: false, wantState: breaker.StateOpen},
+			},
+			wantChanges: []string{"Closed->Open"},
+		},
+		{
+			name: "allows single probe after cooldown and closes on success",
+			steps: []step{
+				{allow: true, wantAllowed: true, failure: true, wantState: breaker.StateClosed},
+				{allow: true, wantAllowed: true, failure: true, wantState: breaker.StateClosed},
+				{allow: true, wantAllowed: true, failure: true, wantState: breaker.StateOpen},
+				{advance: 30 * time.Second, allow: true, wantAllowed: true, wantState: breaker.StateHalfOpen},
+				{allow: true, wantAllowed: false, wantState: breaker.StateHalfOpen},
+				{success: true, wantState: breaker.StateClosed},
+				{allow: true, wantAllowed: true, wantState: breaker.StateClosed},
+			},
+			wantChanges: []string{"Closed->Open", "Open->HalfOpen", "HalfOpen->Closed"},
+		},
+		{
+			name: "re-opens if probe fails",
+			steps: []step{
+				{allow: true, wantAllowed: true, failure: true, wantState: breaker.StateClosed},
+				{allow: true, wantAllowed: true, failure: true, wantState: breaker.StateClosed},
+				{allow: true, wantAllowed: true, failure: true, wantState: breaker.StateOpen},
+				{advance: 30 * time.Second, allow: true, wantAllowed: true, failure: true, wantState: breaker.StateOpen},
+				{advance: 29 * time.Second, allow: true, wantAllowed: false, wantState: breaker.StateOpen},
+			},
+			wantChanges: []string{"Closed->Open", "Open->HalfOpen", "HalfOpen->Open"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			now := time.Date(2026, 2, 17, 23, 0, 0, 0, time.UTC)
+			breaker.Now = func() time.Time { return now }
+			t.Cleanup(func() { breaker.Now = time.Now })
+
+			changes := make([]string, 0)
+			b := breaker.New(3, 30*time.Second, func(from, to breaker.State) {
+				changes = append(changes, from.String()+"->"+to.String())
+			})
+
+			for i, s := range tt.steps {
+				// WHEN
+				now = now.Add(s.advance)
+				if s.allow {
+					assert.Equal(t, s.wantAllowed, b.Allow(), "step %d", i)
+				}
+				if s.success {
+					b.Success()
+				}
+				if s.failure {
+					b.Failure()
+				}
+
+				// THEN
+				assert.Equal(t, s.wantState, b.State(), "step %d", i)
+			}
+
+			assert.Equal(t, append([]string{}, tt.wantChanges...), changes)
+		})
+	}
+}
@@ -1,16 +1,61 @@
 package config
 
 import (
+	"cmp"
+	"errors"
+	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/cetteup/playerpath/internal/domain/provider"
 )
 
+// defaultMirrorThreshold Threshold the handler considers a mirrored upstream URL unhealthy at if none is configured
+const defaultMirrorThreshold = 3
+
 type Config struct {
 	Database DatabaseConfig `yaml:"db"`
-	Servers  []ServerConfig `yaml:"servers"`
+	// Provider to use as fallback if one cannot be selected based on player/server (takes precedence over -provider)
+	Provider provider.Provider `yaml:"-"`
+	// Whether to replace malformed upstream responses with an ASP error response (takes precedence over -validate-responses)
+	ValidateResponses *bool              `yaml:"validate_responses"`
+	Servers           []ServerConfig     `yaml:"-"`
+	Failover          FailoverConfig     `yaml:"failover"`
+	Breaker           BreakerConfig      `yaml:"breaker"`
+	Cache             CacheConfig        `yaml:"cache"`
+	Fallback          FallbackConfig     `yaml:"fallback"`
+	Discovery         DiscoveryConfig    `yaml:"-"`
+	Verification      VerificationConfig `yaml:"verification"`
+	Admin             AdminConfig        `yaml:"admin"`
+	Tracing           TracingConfig      `yaml:"tracing"`
+	// Upstreams Where to forward each provider's requests to, overriding the providers' base URLs
+	Upstreams map[provider.Provider]UpstreamConfig `yaml:"-"`
+	// Providers Catalogue of providers to forward requests to (added to the built-in catalogue, replacing built-in providers with the same ID)
+	Providers []provider.Definition `yaml:"providers"`
+	// Catalogue Built-in providers merged with the ones defined in the config, to be loaded once the config has been applied
+	Catalogue []provider.Definition `yaml:"-"`
+}
+
+// rawConfig Config as read from the file, referencing providers by name. The names can only be resolved
+// once the providers defined in the config are known, which must not affect the catalogue of known providers.
+type rawConfig struct {
+	Config    `yaml:",inline"`
+	Provider  string                    `yaml:"provider"`
+	Servers   []rawServerConfig         `yaml:"servers"`
+	Discovery rawDiscoveryConfig        `yaml:"discovery"`
+	Upstreams map[string]UpstreamConfig `yaml:"upstreams"`
+}
+
+type rawServerConfig struct {
+	IP       string `yaml:"ip"`
+	Provider string `yaml:"provider"`
+}
+
+type rawDiscoveryConfig struct {
+	DiscoveryConfig `yaml:",inline"`
+	Providers       []string `yaml:"providers"`
 }
 
 type DatabaseConfig struct {
@@ -21,21 +66,217 @@ type DatabaseConfig struct {
 }
 
 type ServerConfig struct {
-	IP       string            `yaml:"ip"`
-	Provider provider.Provider `yaml:"provider"`
+	IP       string
+	Provider provider.Provider
+}
+
+type FailoverConfig struct {
+	// Endpoints for which to fail over to the server's/default provider (e.g. getunlocksinfo.aspx)
+	Endpoints []string `yaml:"endpoints"`
+	// Timeout per attempt, ensuring the overall request timeout is not used up by a single provider
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type BreakerConfig struct {
+	// Number of consecutive failures after which to stop forwarding requests to a provider (0 to disable)
+	Threshold int `yaml:"threshold"`
+	// Time to wait before probing a provider again
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+type CacheConfig struct {
+	// Maximum number of cached responses
+	Size int `yaml:"size"`
+	// Endpoints for which to cache responses (e.g. getrankinfo.aspx)
+	Endpoints map[string]CacheEndpointConfig `yaml:"endpoints"`
+	// Players caches player lookups in front of the database
+	Players PlayerCacheConfig `yaml:"players"`
+}
+
+type CacheEndpointConfig struct {
+	// Duration for which responses are served from cache
+	TTL time.Duration `yaml:"ttl"`
+	// Duration after the TTL for which responses are still served from cache while being refreshed in the background
+	Stale time.Duration `yaml:"stale"`
+}
+
+type PlayerCacheConfig struct {
+	// Maximum number of cached players
+	Size int `yaml:"size"`
+	// Duration for which found players are cached (0 to disable)
+	TTL time.Duration `yaml:"ttl"`
+	// Duration for which players are cached as not found
+	Negative time.Duration `yaml:"negative"`
+	// Interval at which to check whether players were imported by the importer, clearing the cache if so (defaults to 30s)
+	Poll time.Duration `yaml:"poll"`
+}
+
+type FallbackConfig struct {
+	// Endpoints for which to serve the last good response if the upstream fails (e.g. getrankinfo.aspx)
+	Endpoints []string `yaml:"endpoints"`
+	// Refresh the asof timestamp of served last good responses
+	Refresh bool `yaml:"refresh"`
+}
+
+type DiscoveryConfig struct {
+	// Providers to probe for players which have not been imported (yet)
+	Providers []provider.Provider `yaml:"-"`
+	// Time to wait for providers to respond
+	Timeout time.Duration `yaml:"timeout"`
+	// Duration for which to remember discovery results (including players not known to any provider)
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type VerificationConfig struct {
+	// Record successful player verifications and route players to the provider which verified them
+	History bool `yaml:"history"`
+}
+
+type AdminConfig struct {
+	// Address to serve the admin API on in format [host]:port (disabled if empty)
+	Address string `yaml:"address"`
+	// Token to be sent as bearer token with every admin API request
+	Token string `yaml:"token"`
+}
+
+type UpstreamConfig struct {
+	// Base URL to forward requests to instead of the provider's (e.g. a staging backend or local mock)
+	URL string `yaml:"url"`
+	// Alternative base URLs to forward requests to (in order) while the preceding ones are unhealthy
+	Mirrors []string `yaml:"mirrors"`
+	// Number of consecutive failures (connection errors, timeouts or 5xx responses) after which a URL is considered unhealthy
+	// (must be below the breaker threshold if the breaker is enabled)
+	Threshold int `yaml:"threshold"`
+	// Time after which an unhealthy URL is tried again
+	Cooldown time.Duration `yaml:"cooldown"`
+	// Settings for the connections to the provider (uses the shared defaults if omitted)
+	Transport *TransportConfig `yaml:"transport"`
+}
+
+type TransportConfig struct {
+	// Time to wait for a connection to be established
+	DialTimeout time.Duration `yaml:"dial_timeout"`
+	// Time to wait for the TLS handshake
+	TLSHandshakeTimeout time.Duration `yaml:"tls_timeout"`
+	// Time to wait for the response headers after sending the request
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout"`
+	// Maximum number of idle (keep-alive) connections to keep open
+	MaxIdleConns int `yaml:"max_idle_conns"`
+	// URL of an HTTP(S) or SOCKS5 proxy to send requests through (e.g. socks5://127.0.0.1:1080)
+	Proxy string `yaml:"proxy"`
+	// Path to a PEM bundle of CA certificates to trust in addition to the system's
+	CAFile string `yaml:"ca_file"`
 }
 
+type TracingConfig struct {
+	// OTLP/HTTP endpoint to export traces to in format host:port (disabled if empty)
+	Endpoint string `yaml:"endpoint"`
+	// Export traces via plain HTTP instead of HTTPS
+	Insecure bool `yaml:"insecure"`
+	// Fraction of requests to trace, between 0 and 1 (defaults to 1 if unset, 0 disables sampling)
+	Ratio *float64 `yaml:"ratio"`
+}
+
+// LoadConfig Read and validate the config, resolving provider names using the built-in providers plus the ones
+// defined in the config. The catalogue of known providers is left untouched, see Config.Catalogue.
 func LoadConfig(path string) (Config, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return Config{}, err
 	}
 
-	var config Config
-	err = yaml.Unmarshal(content, &config)
+	var raw rawConfig
+	if err = yaml.Unmarshal(content, &raw); err != nil {
+		return Config{}, err
+	}
+
+	config, err := raw.resolve()
 	if err != nil {
 		return Config{}, err
 	}
 
+	if err = config.validate(); err != nil {
+		return Config{}, err
+	}
+
 	return config, nil
 }
+
+// resolve Resolve all provider names against the catalogue the config describes
+func (r rawConfig) resolve() (Config, error) {
+	config := r.Config
+	config.Catalogue = provider.Merge(provider.Defaults, config.Providers)
+	if err := provider.Validate(config.Catalogue); err != nil {
+		return Config{}, err
+	}
+
+	var err error
+	if config.Provider, err = provider.Parse(config.Catalogue, r.Provider); err != nil {
+		return Config{}, err
+	}
+
+	config.Servers = make([]ServerConfig, 0, len(r.Servers))
+	for _, server := range r.Servers {
+		pv, err2 := provider.Parse(config.Catalogue, server.Provider)
+		if err2 != nil {
+			return Config{}, err2
+		}
+		config.Servers = append(config.Servers, ServerConfig{IP: server.IP, Provider: pv})
+	}
+
+	config.Discovery = r.Discovery.DiscoveryConfig
+	for _, name := range r.Discovery.Providers {
+		pv, err2 := provider.Parse(config.Catalogue, name)
+		if err2 != nil {
+			return Config{}, err2
+		}
+		config.Discovery.Providers = append(config.Discovery.Providers, pv)
+	}
+
+	if len(r.Upstreams) > 0 {
+		config.Upstreams = make(map[provider.Provider]UpstreamConfig, len(r.Upstreams))
+		for name, upstream := range r.Upstreams {
+			pv, err2 := provider.Parse(config.Catalogue, name)
+			if err2 != nil {
+				return Config{}, err2
+			}
+			config.Upstreams[pv] = upstream
+		}
+	}
+
+	return config, nil
+}
+
+func (c Config) validate() error {
+	servers := make(map[string]struct{}, len(c.Servers))
+	for _, server := range c.Servers {
+		if server.IP == "" {
+			return errors.New("server ip must not be empty")
+		}
+		if server.Provider == provider.Unknown {
+			return fmt.Errorf("provider must be set for server %s", server.IP)
+		}
+		if _, exists := servers[server.IP]; exists {
+			return fmt.Errorf("duplicate server: %s", server.IP)
+		}
+		servers[server.IP] = struct{}{}
+	}
+
+	// The breaker counts failures across all of a provider's URLs, so it must not trip before the mirrors are tried
+	if c.Breaker.Threshold > 0 {
+		for pv, upstream := range c.Upstreams {
+			if len(upstream.Mirrors) == 0 {
+				continue
+			}
+			threshold := cmp.Or(upstream.Threshold, defaultMirrorThreshold)
+			if threshold >= c.Breaker.Threshold {
+				return fmt.Errorf(
+					"upstream threshold for provider %s (%d) must be below breaker threshold (%d)",
+					pv, threshold, c.Breaker.Threshold,
+				)
+			}
+		}
+	}
+
+	return nil
+}
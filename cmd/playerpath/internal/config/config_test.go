@@ -0,0 +1,77 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/config"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+func TestLoadConfig(t *testing.T) {
+	// WHEN
+	cfg, err := config.LoadConfig("../../../../config.example.yaml")
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, "db", cfg.Database.Hostname)
+	assert.Equal(t, 5, cfg.Breaker.Threshold)
+	assert.Equal(t, time.Minute, cfg.Cache.Endpoints["getrankinfo.aspx"].TTL)
+	require.Len(t, cfg.Providers, 1)
+	assert.Equal(t, "league", cfg.Providers[0].Name)
+	assert.Contains(t, cfg.Upstreams, provider.BF2Hub)
+	assert.Contains(t, cfg.Upstreams, cfg.Providers[0].Provider())
+	assert.Contains(t, cfg.Catalogue, cfg.Providers[0])
+	// Providers defined in the config must only become known once the config has been applied
+	assert.Equal(t, provider.Defaults, provider.All())
+}
+
+func TestLoadConfig_UpstreamThreshold(t *testing.T) {
+	tests := []struct {
+		name            string
+		givenConfig     string
+		wantErrContains string
+	}{
+		{
+			name:        "accepts mirror threshold below breaker threshold",
+			givenConfig: "breaker:\n  threshold: 5\nupstreams:\n  bf2hub:\n    mirrors:\n      - http://mirror.example.com/\n",
+		},
+		{
+			name:        "accepts any threshold without mirrors",
+			givenConfig: "breaker:\n  threshold: 2\nupstreams:\n  bf2hub:\n    url: http://staging.example.com/\n",
+		},
+		{
+			name:            "fails for default mirror threshold at breaker threshold",
+			givenConfig:     "breaker:\n  threshold: 3\nupstreams:\n  bf2hub:\n    mirrors:\n      - http://mirror.example.com/\n",
+			wantErrContains: "must be below breaker threshold",
+		},
+		{
+			name:            "fails for mirror threshold above breaker threshold",
+			givenConfig:     "breaker:\n  threshold: 5\nupstreams:\n  bf2hub:\n    mirrors:\n      - http://mirror.example.com/\n    threshold: 10\n",
+			wantErrContains: "must be below breaker threshold",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.givenConfig), 0o600))
+
+			// WHEN
+			_, err := config.LoadConfig(path)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				assert.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/cetteup/playerpath/internal/domain/player"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+type candidateDTO struct {
+	Nick     string            `json:"nick"`
+	Provider provider.Provider `json:"provider"`
+	Imported time.Time         `json:"imported"`
+}
+
+type playerDTO struct {
+	PID        int               `json:"pid"`
+	Provider   provider.Provider `json:"provider"`
+	Reason     string            `json:"reason"`
+	Candidates []candidateDTO    `json:"candidates"`
+}
+
+type serverDTO struct {
+	IP       string            `json:"ip"`
+	Provider provider.Provider `json:"provider"`
+}
+
+type serversDTO struct {
+	Default provider.Provider `json:"default"`
+	Servers []serverDTO       `json:"servers"`
+}
+
+type overrideDTO struct {
+	PID      int               `json:"pid"`
+	Provider provider.Provider `json:"provider"`
+	Author   string            `json:"author"`
+	Reason   string            `json:"reason"`
+	Updated  time.Time         `json:"updated"`
+}
+
+func newOverrideDTO(o player.Override) overrideDTO {
+	return overrideDTO{
+		PID:      o.PID,
+		Provider: o.Provider,
+		Author:   o.Author,
+		Reason:   o.Reason,
+		Updated:  o.Updated,
+	}
+}
+
+// HandleListOverrides List all manual player provider overrides
+func (h *Handler) HandleListOverrides(c echo.Context) error {
+	overrides, err := h.repository.FindOverrides(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	dtos := make([]overrideDTO, 0, len(overrides))
+	for _, o := range overrides {
+		dtos = append(dtos, newOverrideDTO(o))
+	}
+
+	return c.JSON(http.StatusOK, dtos)
+}
+
+// HandleGetOverride Get the manual provider override for a player
+func (h *Handler) HandleGetOverride(c echo.Context) error {
+	params := struct {
+		PID int `param:"pid"`
+	}{}
+	if err := c.Bind(&params); err != nil {
+		return err
+	}
+
+	o, err := h.repository.FindOverrideByPID(c.Request().Context(), params.PID)
+	if err != nil {
+		if errors.Is(err, player.ErrOverrideNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return err
+	}
+
+	return c.JSON(http.StatusOK, newOverrideDTO(o))
+}
+
+// HandleSetOverride Pin a player to a provider, regardless of any imported players using the PID
+func (h *Handler) HandleSetOverride(c echo.Context) error {
+	params := struct {
+		PID      int               `param:"pid"`
+		Provider provider.Provider `json:"provider"`
+		Author   string            `json:"author"`
+		Reason   string            `json:"reason"`
+	}{}
+	if err := c.Bind(&params); err != nil {
+		return err
+	}
+
+	if params.Provider == provider.Unknown || params.Author == "" || params.Reason == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "provider, author and reason are required")
+	}
+
+	o := player.Override{
+		PID:      params.PID,
+		Provider: params.Provider,
+		Author:   params.Author,
+		Reason:   params.Reason,
+		Updated:  time.Now().UTC(),
+	}
+	if err := h.repository.UpsertOverride(c.Request().Context(), o); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, newOverrideDTO(o))
+}
+
+// HandleDeleteOverride Remove the manual provider override for a player
+func (h *Handler) HandleDeleteOverride(c echo.Context) error {
+	params := struct {
+		PID int `param:"pid"`
+	}{}
+	if err := c.Bind(&params); err != nil {
+		return err
+	}
+
+	if err := h.repository.DeleteOverride(c.Request().Context(), params.PID); err != nil {
+		if errors.Is(err, player.ErrOverrideNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HandleGetPlayer Determine the provider for a player as if a server had made a request for the player,
+// optionally passing the server's IP and the player's nick (as sent along with verification requests).
+// Players are not discovered via the providers, so the result may differ for players which have not been imported yet.
+func (h *Handler) HandleGetPlayer(c echo.Context) error {
+	params := struct {
+		PID    int    `param:"pid"`
+		Server string `query:"server"`
+		Nick   string `query:"nick"`
+	}{}
+	if err := c.Bind(&params); err != nil {
+		return err
+	}
+
+	d, err := h.determineProvider(c.Request().Context(), params.PID, params.Nick, params.Server, true)
+	if err != nil {
+		return err
+	}
+
+	candidates := make([]candidateDTO, 0, len(d.Candidates))
+	for _, p := range d.Candidates {
+		candidates = append(candidates, candidateDTO{
+			Nick:     p.Nick,
+			Provider: p.Provider,
+			Imported: p.Imported,
+		})
+	}
+
+	return c.JSON(http.StatusOK, playerDTO{
+		PID:        params.PID,
+		Provider:   d.Provider,
+		Reason:     string(d.Reason),
+		Candidates: candidates,
+	})
+}
+
+// HandleListServers List the configured servers along with the default provider
+func (h *Handler) HandleListServers(c echo.Context) error {
+	settings := h.getSettings()
+	dtos := make([]serverDTO, 0, len(settings.servers))
+	for ip, pv := range settings.servers {
+		dtos = append(dtos, serverDTO{
+			IP:       ip,
+			Provider: pv,
+		})
+	}
+	slices.SortFunc(dtos, func(a, b serverDTO) int {
+		return cmp.Compare(a.IP, b.IP)
+	})
+
+	return c.JSON(http.StatusOK, serversDTO{
+		Default: settings.provider,
+		Servers: dtos,
+	})
+}
+
+// HandleGetCaches Get the number of entries in each enabled in-memory cache
+func (h *Handler) HandleGetCaches(c echo.Context) error {
+	caches := map[string]int{
+		"history": h.history.Len(),
+	}
+	if h.responses != nil {
+		caches["responses"] = h.responses.Len()
+	}
+	if h.discovery.results != nil {
+		caches["discovery"] = h.discovery.results.Len()
+	}
+	if h.verifications.providers != nil {
+		caches["verifications"] = h.verifications.providers.Len()
+	}
+
+	return c.JSON(http.StatusOK, caches)
+}
+
+// HandleGetBreakers Get the state of each provider's circuit breaker (only includes providers requests were made to)
+func (h *Handler) HandleGetBreakers(c echo.Context) error {
+	h.breakers.Lock()
+	defer h.breakers.Unlock()
+
+	breakers := make(map[string]string, len(h.breakers.byProvider))
+	for pv, b := range h.breakers.byProvider {
+		breakers[pv.String()] = b.State().String()
+	}
+
+	return c.JSON(http.StatusOK, breakers)
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/asp"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/trace"
+)
+
+const (
+	revalidateTimeout = 10 * time.Second
+)
+
+type CacheTTL struct {
+	// TTL Duration for which a response is served from cache
+	TTL time.Duration
+	// Stale Duration after the TTL for which a response is still served from cache while being revalidated
+	Stale time.Duration
+}
+
+// getCacheKey Determine the cache key for the request (false if responses for the endpoint are not cached)
+func (h *Handler) getCacheKey(pv provider.Provider, dr downstreamRequest) (string, bool) {
+	if h.responses == nil {
+		return "", false
+	}
+
+	endpoint := strings.ToLower(path.Base(dr.Path))
+	if _, ok := h.cacheTTLs[endpoint]; !ok {
+		return "", false
+	}
+
+	query, err := normalizeQuery(dr.RawQuery)
+	if err != nil {
+		return "", false
+	}
+
+	return strings.Join([]string{pv.String(), endpoint, query}, "|"), true
+}
+
+// normalizeQuery Normalize the query by lower-casing keys (Encode takes care of sorting)
+func normalizeQuery(rawQuery string) (string, error) {
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", err
+	}
+
+	normalized := make(url.Values, len(q))
+	for key, values := range q {
+		normalized[strings.ToLower(key)] = append(normalized[strings.ToLower(key)], values...)
+	}
+
+	return normalized.Encode(), nil
+}
+
+// storeResponse Cache the response, provided it is a successful ASP response
+func (h *Handler) storeResponse(key string, p string, res UpstreamResponse) {
+	if res.StatusCode != http.StatusOK {
+		return
+	}
+
+	r, err := asp.Parse(string(res.Body))
+	if err != nil || !r.OK() {
+		return
+	}
+
+	ttl := h.cacheTTLs[strings.ToLower(path.Base(p))]
+	h.responses.Set(key, res, ttl.TTL, ttl.Stale)
+}
+
+// revalidate Refresh a stale cached response in the background (unless it's already being refreshed)
+func (h *Handler) revalidate(ctx context.Context, key string, pv provider.Provider, dr downstreamRequest) {
+	if !h.responses.Revalidate(key) {
+		return
+	}
+
+	// Downstream request will have been completed by the time the revalidation request is made
+	ctx = context.WithoutCancel(ctx)
+	dr.Header = dr.Header.Clone()
+
+	go func() {
+		defer h.responses.Done(key)
+
+		ctx, cancel := context.WithTimeout(ctx, revalidateTimeout)
+		defer cancel()
+
+		res, err := h.forward(ctx, pv, dr)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Stringer(trace.LogProvider, pv).
+				Str("path", dr.Path).
+				Msg("Failed to revalidate cached response")
+			return
+		}
+
+		h.storeResponse(key, dr.Path, res)
+	}()
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/metrics"
+	"github.com/cetteup/playerpath/internal/domain/player"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+const (
+	HeaderProvider   = "X-Playerpath-Provider"
+	HeaderReason     = "X-Playerpath-Reason"
+	HeaderCandidates = "X-Playerpath-Candidates"
+	// HeaderServed Provider which actually served the request, only differs from HeaderProvider after a failover
+	HeaderServed = "X-Playerpath-Served"
+)
+
+// reason Why a provider was chosen for a request
+type reason string
+
+const (
+	reasonOverride     reason = "override"
+	reasonVerification reason = "verification"
+	reasonPlayer       reason = "player"
+	reasonDiscovery    reason = "discovery"
+	// reasonNick, reasonHistory and reasonServerPlayer indicate the rule used to choose between players sharing a PID
+	reasonNick         reason = "nick"
+	reasonHistory      reason = "history"
+	reasonServerPlayer reason = "server-player"
+	reasonServer       reason = "server"
+	reasonDefault      reason = "default"
+)
+
+// decision Provider chosen for a request, along with why it was chosen and the players which were considered
+type decision struct {
+	Provider   provider.Provider
+	Reason     reason
+	Candidates []player.Player
+}
+
+// MarshalZerologObject Add the decision to a log event, e.g. the request log
+func (d decision) MarshalZerologObject(e *zerolog.Event) {
+	e.Stringer("provider", d.Provider).Str("reason", string(d.Reason))
+	if len(d.Candidates) > 0 {
+		e.Strs("candidates", d.describeCandidates())
+	}
+}
+
+// describeCandidates Describe the considered players in format nick@provider
+func (d decision) describeCandidates() []string {
+	described := make([]string, 0, len(d.Candidates))
+	for _, p := range d.Candidates {
+		described = append(described, p.Nick+"@"+p.Provider.String())
+	}
+	return described
+}
+
+// applyDecision Make the decision available to request logging, metrics and (if enabled) debug response headers
+func (h *Handler) applyDecision(c echo.Context, d decision) {
+	metrics.Requests.WithLabelValues(metrics.Endpoint(c.Request().URL.Path), d.Provider.String(), string(d.Reason)).Inc()
+
+	// Only used for request logging
+	c.Set("provider", d.Provider)
+	c.Set("decision", d)
+
+	if h.debugHeaders {
+		header := c.Response().Header()
+		header.Set(HeaderProvider, d.Provider.String())
+		header.Set(HeaderReason, string(d.Reason))
+		if len(d.Candidates) > 0 {
+			header.Set(HeaderCandidates, strings.Join(d.describeCandidates(), ","))
+		}
+	}
+}
+
+// applyServed Record which provider actually served the request, which may differ from the decision after a failover
+func (h *Handler) applyServed(c echo.Context, served provider.Provider) {
+	// Only used for request logging
+	c.Set("provider", served)
+
+	if h.debugHeaders {
+		c.Response().Header().Set(HeaderServed, served.String())
+	}
+}
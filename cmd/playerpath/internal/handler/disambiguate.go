@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/playerpath/internal/domain/player"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/pkg/cache"
+	"github.com/cetteup/playerpath/internal/trace"
+)
+
+const (
+	historyTTL  = 24 * time.Hour
+	historySize = 10000
+)
+
+// disambiguationRule Rule for choosing between multiple players using the same PID,
+// deciding if exactly one candidate matches
+type disambiguationRule struct {
+	reason  reason
+	matches func(p player.Player) bool
+}
+
+func newHistory() *cache.Cache[provider.Provider] {
+	return cache.New[provider.Provider](historySize)
+}
+
+// choosePlayer Choose the provider among the players using the PID
+// (provider.Unknown if there are none or none can be chosen)
+func (h *Handler) choosePlayer(
+	pid int,
+	nick string,
+	server provider.Provider,
+	candidates []player.Player,
+	inspect bool,
+) (provider.Provider, reason) {
+	switch len(candidates) {
+	case 0:
+		return provider.Unknown, ""
+	case 1:
+		return candidates[0].Provider, reasonPlayer
+	default:
+		return h.disambiguatePlayer(pid, nick, server, candidates, inspect)
+	}
+}
+
+// disambiguatePlayer Choose the provider between multiple players using the same PID based on the nick
+// (only known for some requests), the provider recently chosen by nick and the server's provider.
+// When inspecting, the choice is neither remembered nor logged.
+func (h *Handler) disambiguatePlayer(
+	pid int,
+	nick string,
+	server provider.Provider,
+	candidates []player.Player,
+	inspect bool,
+) (provider.Provider, reason) {
+	recent, _ := h.history.Get(strconv.Itoa(pid))
+	rules := []disambiguationRule{
+		{
+			reason: reasonNick,
+			matches: func(p player.Player) bool {
+				return nick != "" && strings.EqualFold(p.Nick, nick)
+			},
+		},
+		{
+			reason: reasonHistory,
+			matches: func(p player.Player) bool {
+				return recent != provider.Unknown && p.Provider == recent
+			},
+		},
+		{
+			reason: reasonServerPlayer,
+			matches: func(p player.Player) bool {
+				return server != provider.Unknown && p.Provider == server
+			},
+		},
+	}
+
+	for _, rule := range rules {
+		var match *player.Player
+		n := 0
+		for _, candidate := range candidates {
+			if rule.matches(candidate) {
+				match = &candidate
+				n++
+			}
+		}
+		if n != 1 {
+			continue
+		}
+
+		if inspect {
+			return match.Provider, rule.reason
+		}
+
+		// Only remember decisions based on the nick, since the other rules do not add any information
+		if rule.reason == reasonNick {
+			h.history.Set(strconv.Itoa(pid), match.Provider, historyTTL, 0)
+		}
+
+		log.Info().
+			Int(trace.LogPlayerPID, pid).
+			Stringer(trace.LogProvider, match.Provider).
+			Str("rule", string(rule.reason)).
+			Int("candidates", len(candidates)).
+			Msg("Resolved multiple players using PID")
+		return match.Provider, rule.reason
+	}
+
+	if !inspect {
+		log.Warn().
+			Int(trace.LogPlayerPID, pid).
+			Int("candidates", len(candidates)).
+			Msg("Found multiple players, deferring provider selection")
+	}
+	return provider.Unknown, ""
+}
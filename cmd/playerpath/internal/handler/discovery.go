@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/asp"
+	"github.com/cetteup/playerpath/internal/domain/player"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/pkg/cache"
+	"github.com/cetteup/playerpath/internal/trace"
+)
+
+const (
+	discoveryPath      = "/ASP/getplayerinfo.aspx"
+	discoveryCacheSize = 10000
+	discoveryUserAgent = "GameSpyHTTP/1.0"
+)
+
+type playerDiscovery struct {
+	providers []provider.Provider
+	timeout   time.Duration
+	ttl       time.Duration
+	results   *cache.Cache[[]player.Player]
+	// group Ensures a player is only probed once at a time, since servers request rank, unlocks and awards all at once
+	group singleflight.Group
+}
+
+// discoverPlayer Probe providers for the player, persisting any player found.
+// Returns the player for each provider which knows the PID (none if discovery is disabled).
+func (h *Handler) discoverPlayer(ctx context.Context, pid int, serverIP string) ([]player.Player, error) {
+	if len(h.discovery.providers) == 0 {
+		return nil, nil
+	}
+
+	// Results are cached regardless of whether the player was found, else any unknown player would be probed over and over
+	key := strconv.Itoa(pid)
+	if found, freshness := h.discovery.results.Get(key); freshness == cache.Fresh {
+		return found, nil
+	}
+
+	// Probe using a detached context, since the result is shared with any concurrent requests for the player
+	result, err, _ := h.discovery.group.Do(key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), h.discovery.timeout)
+		defer cancel()
+
+		return h.probePlayer(ctx, pid, serverIP), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	found := result.([]player.Player)
+	h.discovery.results.Set(key, found, h.discovery.ttl, 0)
+
+	if len(found) == 0 {
+		log.Warn().
+			Int(trace.LogPlayerPID, pid).
+			Msg("Player not found by any provider")
+		return found, nil
+	}
+
+	if _, err = h.repository.UpsertMany(ctx, found); err != nil {
+		log.Error().
+			Err(err).
+			Int(trace.LogPlayerPID, pid).
+			Msg("Failed to persist discovered player")
+	}
+
+	log.Info().
+		Int(trace.LogPlayerPID, pid).
+		Int("candidates", len(found)).
+		Msg("Discovered player")
+
+	return found, nil
+}
+
+// probePlayer Concurrently request the player's info from all providers, returning the player for each provider
+// which knows the PID
+func (h *Handler) probePlayer(ctx context.Context, pid int, serverIP string) []player.Player {
+	q := url.Values{}
+	q.Set("pid", strconv.Itoa(pid))
+	q.Set("info", "rank")
+
+	dr := downstreamRequest{
+		Method:   http.MethodGet,
+		Path:     discoveryPath,
+		RawQuery: q.Encode(),
+		Proto:    "HTTP/1.1",
+		Header:   http.Header{"User-Agent": []string{discoveryUserAgent}},
+		RemoteIP: serverIP,
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	found := make([]player.Player, 0, 1)
+	for _, pv := range h.discovery.providers {
+		wg.Go(func() {
+			res, err := h.forward(ctx, pv, dr)
+			if err != nil {
+				log.Debug().
+					Err(err).
+					Int(trace.LogPlayerPID, pid).
+					Stringer(trace.LogProvider, pv).
+					Msg("Failed to probe provider for player")
+				return
+			}
+
+			// Providers respond with an error (usually 104, no data) for players they do not know
+			r, err := asp.Parse(string(res.Body))
+			if err != nil || !r.OK() {
+				return
+			}
+
+			nick, _ := r.Get("nick")
+
+			mu.Lock()
+			defer mu.Unlock()
+			found = append(found, player.Player{
+				PID:      pid,
+				Nick:     nick,
+				Provider: pv,
+				Imported: time.Now().UTC(),
+			})
+		})
+	}
+	wg.Wait()
+
+	return found
+}
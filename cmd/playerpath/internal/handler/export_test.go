@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/cetteup/playerpath/internal/domain/player"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+// Expose unexported methods to the external test package
+
+func (h *Handler) DetermineProvider(
+	ctx context.Context,
+	pid int,
+	nick string,
+	serverIP string,
+	inspect bool,
+) (provider.Provider, string, error) {
+	d, err := h.determineProvider(ctx, pid, nick, serverIP, inspect)
+	return d.Provider, string(d.Reason), err
+}
+
+func (h *Handler) DisambiguatePlayer(
+	pid int,
+	nick string,
+	server provider.Provider,
+	candidates []player.Player,
+	inspect bool,
+) (provider.Provider, string) {
+	pv, r := h.disambiguatePlayer(pid, nick, server, candidates, inspect)
+	return pv, string(r)
+}
+
+func (h *Handler) PrepareSnapshotPayloads(
+	ctx context.Context,
+	body []byte,
+	primary provider.Provider,
+) (map[provider.Provider][]byte, error) {
+	return h.prepareSnapshotPayloads(ctx, body, primary)
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/asp"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/domain/response"
+	"github.com/cetteup/playerpath/internal/pkg/cache"
+	"github.com/cetteup/playerpath/internal/trace"
+)
+
+const (
+	fallbackStoreTimeout = 5 * time.Second
+	fallbackDigestTTL    = 24 * time.Hour
+	fallbackDigestSize   = 10000
+)
+
+// shouldFallback Determine whether the last good response should be served for the endpoint if the upstream fails
+func (h *Handler) shouldFallback(p string) bool {
+	if h.fallback.repository == nil {
+		return false
+	}
+
+	_, ok := h.fallback.endpoints[strings.ToLower(path.Base(p))]
+	return ok
+}
+
+// storeFallbackResponse Persist the response as the last good response in the background,
+// provided it is a successful ASP response which differs from the one last persisted
+func (h *Handler) storeFallbackResponse(
+	ctx context.Context,
+	pid int,
+	pv provider.Provider,
+	dr downstreamRequest,
+	res UpstreamResponse,
+) {
+	if res.StatusCode != http.StatusOK {
+		return
+	}
+
+	r, err := asp.Parse(string(res.Body))
+	if err != nil || !r.OK() {
+		return
+	}
+
+	queryHash, ok := getQueryHash(dr.RawQuery)
+	if !ok {
+		return
+	}
+
+	// Responses usually only differ by their asof timestamp, which is not worth rewriting the stored response for
+	r.Set("asof", "")
+	digest := fmt.Sprintf("%x", sha256.Sum256([]byte(r.Serialize())))
+	endpoint := strings.ToLower(path.Base(dr.Path))
+	key := strings.Join([]string{strconv.Itoa(pid), pv.String(), endpoint, queryHash}, "|")
+	if stored, freshness := h.fallback.digests.Get(key); freshness == cache.Fresh && stored == digest {
+		return
+	}
+	h.fallback.digests.Set(key, digest, fallbackDigestTTL, 0)
+
+	// Downstream request will have been completed by the time the response is persisted
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, fallbackStoreTimeout)
+		defer cancel()
+
+		err := h.fallback.repository.Upsert(ctx, response.Response{
+			PID:       pid,
+			Provider:  pv,
+			Endpoint:  endpoint,
+			QueryHash: queryHash,
+			Body:      res.Body,
+			Updated:   time.Now().UTC(),
+		})
+		if err != nil {
+			// Ensure the response is persisted with the next request
+			h.fallback.digests.Delete(key)
+			log.Error().
+				Err(err).
+				Int(trace.LogPlayerPID, pid).
+				Stringer(trace.LogProvider, pv).
+				Str("path", dr.Path).
+				Msg("Failed to persist last good response")
+		}
+	}()
+}
+
+// getFallbackResponse Get the last good response for the player from the provider to the same request
+// (false if there is none)
+func (h *Handler) getFallbackResponse(ctx context.Context, pid int, pv provider.Provider, dr downstreamRequest) (string, bool) {
+	queryHash, ok := getQueryHash(dr.RawQuery)
+	if !ok {
+		return "", false
+	}
+
+	res, err := h.fallback.repository.Find(ctx, pid, pv, strings.ToLower(path.Base(dr.Path)), queryHash)
+	if err != nil {
+		if !errors.Is(err, response.ErrResponseNotFound) {
+			log.Error().
+				Err(err).
+				Int(trace.LogPlayerPID, pid).
+				Stringer(trace.LogProvider, pv).
+				Str("path", dr.Path).
+				Msg("Failed to retrieve last good response")
+		}
+		return "", false
+	}
+	if !h.fallback.refresh {
+		return string(res.Body), true
+	}
+
+	r, err := asp.Parse(string(res.Body))
+	if err != nil {
+		// Should never happen, since only valid responses are persisted
+		return string(res.Body), true
+	}
+
+	r.Set("asof", asp.Timestamp())
+
+	return r.Serialize(), true
+}
+
+// getQueryHash Get the digest of the normalized query, since responses may differ by query (e.g. getplayerinfo.aspx's info)
+func getQueryHash(rawQuery string) (string, bool) {
+	query, err := normalizeQuery(rawQuery)
+	if err != nil {
+		return "", false
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(query))), true
+}
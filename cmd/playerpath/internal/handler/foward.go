@@ -1,18 +1,56 @@
 package handler
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
-	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
 
 	"github.com/cetteup/playerpath/cmd/playerpath/internal/asp"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/breaker"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/metrics"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/modify"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/tracing"
 	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/pkg/cache"
 	"github.com/cetteup/playerpath/internal/trace"
 )
 
+// downstreamRequest Details of a request received from a game server, as required to forward the request
+type downstreamRequest struct {
+	Method   string
+	Path     string
+	RawQuery string
+	Proto    string
+	Header   http.Header
+	RemoteIP string
+	Body     []byte
+}
+
+func newDownstreamRequest(c echo.Context, body []byte) downstreamRequest {
+	return downstreamRequest{
+		Method:   c.Request().Method,
+		Path:     c.Request().URL.Path,
+		RawQuery: c.Request().URL.RawQuery,
+		Proto:    c.Request().Proto,
+		Header:   c.Request().Header,
+		RemoteIP: c.RealIP(),
+		Body:     body,
+	}
+}
+
 type UpstreamResponse struct {
 	StatusCode int
 	Header     map[string][]string
@@ -29,51 +67,220 @@ func (h *Handler) HandleDynamicForward(c echo.Context) error {
 		return c.String(http.StatusOK, asp.NewSyntaxErrorResponse().Serialize())
 	}
 
-	pv, err := h.determineProvider(c.Request().Context(), params.PID, c.RealIP())
+	// Nick is only sent along with (and can only be parsed from) VerifyPlayer.aspx requests
+	var nick string
+	if strings.EqualFold(path.Base(c.Request().URL.Path), "VerifyPlayer.aspx") {
+		nick = modify.ParseVerifyPlayersQuery(c.Request().URL.RawQuery).Get("SoldierNick")
+	}
+
+	ctx := c.Request().Context()
+	d, err := h.determineProvider(ctx, params.PID, nick, c.RealIP(), false)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
 	}
+	pv := d.Provider
+	h.applyDecision(c, d)
 
-	// Only used for request logging
-	c.Set("provider", pv)
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return err
+	}
 
-	return h.handleForward(c, pv)
+	dr := newDownstreamRequest(c, body)
+	key, cacheable := h.getCacheKey(pv, dr)
+	if cacheable {
+		if res, freshness := h.responses.Get(key); freshness != cache.Miss {
+			if freshness == cache.Stale {
+				h.revalidate(ctx, key, pv, dr)
+			}
+
+			return c.String(res.StatusCode, string(res.Body))
+		}
+	}
+
+	res, served, err := h.forwardDynamic(ctx, pv, dr)
+	if h.shouldFallback(dr.Path) {
+		if failure := checkResponse(res, err); failure != nil {
+			if fallback, ok := h.getFallbackResponse(ctx, params.PID, pv, dr); ok {
+				log.Warn().
+					Err(failure).
+					Int(trace.LogPlayerPID, params.PID).
+					Stringer(trace.LogProvider, pv).
+					Str("path", dr.Path).
+					Msg("Provider failed to respond, serving last good response")
+				return c.String(http.StatusOK, fallback)
+			}
+		} else if served == pv {
+			h.storeFallbackResponse(ctx, params.PID, pv, dr, res)
+		}
+	}
+	if errors.Is(err, breaker.ErrOpen) {
+		// Respond right away rather than letting the server wait for a provider that is known to be down
+		return c.String(http.StatusOK, asp.NewUpstreamErrorResponse().Serialize())
+	}
+	if err != nil {
+		return err
+	}
+
+	h.applyServed(c, served)
+
+	if nick != "" {
+		h.recordVerification(ctx, params.PID, nick, served, c.RealIP(), res)
+	}
+
+	// Only cache responses from the player's provider, failover responses would otherwise stick around
+	if cacheable && served == pv {
+		h.storeResponse(key, dr.Path, res)
+	}
+
+	return c.String(res.StatusCode, string(res.Body))
 }
 
 // HandleStaticForward Handle requests that are forwarded on a per-server basis.
 // Static only in the sense that any request from a given server will be forwarded to the same provider.
 func (h *Handler) HandleStaticForward(c echo.Context) error {
-	pv := h.getServerOrDefaultProvider(c.RealIP())
-
-	// Only used for request logging
-	c.Set("provider", pv)
+	pv, r := h.getServerOrDefaultProvider(c.RealIP())
+	h.applyDecision(c, decision{Provider: pv, Reason: r})
 
 	return h.handleForward(c, pv)
 }
 
 func (h *Handler) handleForward(c echo.Context, pv provider.Provider) error {
-	u, err := url.Parse(provider.GetBaseURL(pv))
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return err
+	}
+
+	res, err := h.forward(c.Request().Context(), pv, newDownstreamRequest(c, body))
+	if errors.Is(err, breaker.ErrOpen) {
+		// Respond right away rather than letting the server wait for a provider that is known to be down
+		return c.String(http.StatusOK, asp.NewUpstreamErrorResponse().Serialize())
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.String(res.StatusCode, string(res.Body))
+}
+
+// forwardDynamic Forward the request to the given provider, failing over to other providers if enabled for the endpoint
+func (h *Handler) forwardDynamic(
+	ctx context.Context,
+	pv provider.Provider,
+	dr downstreamRequest,
+) (UpstreamResponse, provider.Provider, error) {
+	if !h.shouldFailover(dr.Path) {
+		res, err := h.forward(ctx, pv, dr)
+		return res, pv, err
+	}
+
+	return h.forwardWithFailover(ctx, h.determineFailoverProviders(pv, dr.RemoteIP), dr)
+}
+
+// forwardWithFailover Forward the request to each provider in turn, returning the first valid ASP response
+func (h *Handler) forwardWithFailover(
+	ctx context.Context,
+	providers []provider.Provider,
+	dr downstreamRequest,
+) (UpstreamResponse, provider.Provider, error) {
+	var res UpstreamResponse
+	var err error
+	for i, pv := range providers {
+		res, err = h.forwardWithTimeout(ctx, pv, dr, h.failover.timeout)
+		if err == nil {
+			err = validateResponse(res)
+		}
+		if err == nil {
+			return res, pv, nil
+		}
+
+		if i+1 < len(providers) {
+			log.Warn().
+				Err(err).
+				Stringer(trace.LogProvider, pv).
+				Stringer("failover", providers[i+1]).
+				Str("path", dr.Path).
+				Msg("Provider failed to respond, failing over")
+		}
+	}
+
+	// Pass on last response if there is one, even if it's not valid
+	last := providers[len(providers)-1]
+	if res.Body == nil {
+		return UpstreamResponse{}, last, err
+	}
+
+	return res, last, nil
+}
+
+// checkResponse Check whether the upstream successfully responded with a valid ASP response
+func checkResponse(res UpstreamResponse, err error) error {
 	if err != nil {
 		return err
 	}
-	u = u.JoinPath(c.Request().URL.Path)
-	u.RawQuery = c.Request().URL.RawQuery
 
-	req, err := http.NewRequestWithContext(c.Request().Context(), c.Request().Method, u.String(), c.Request().Body)
+	return validateResponse(res)
+}
+
+func validateResponse(res UpstreamResponse) error {
+	r, err := asp.Parse(string(res.Body))
 	if err != nil {
 		return err
 	}
 
+	// Invalid responses may already have been replaced by a response modifier
+	if code, ok := r.ErrorCode(); ok && code == asp.ErrorCodeUpstream {
+		return errors.New("upstream error response")
+	}
+
+	return nil
+}
+
+func (h *Handler) forwardWithTimeout(
+	ctx context.Context,
+	pv provider.Provider,
+	dr downstreamRequest,
+	timeout time.Duration,
+) (UpstreamResponse, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return h.forward(ctx, pv, dr)
+}
+
+func (h *Handler) forward(ctx context.Context, pv provider.Provider, dr downstreamRequest) (UpstreamResponse, error) {
+	// Use the same modifiers for request and response, even if the settings are replaced in between
+	settings := h.getSettings()
+
+	m, err := h.selectUpstream(pv)
+	if err != nil {
+		return UpstreamResponse{}, err
+	}
+	u := m.url.JoinPath(dr.Path)
+	u.RawQuery = dr.RawQuery
+
+	// Passing the body as a bytes.Reader sets the content length, avoiding chunked encoding
+	req, err := http.NewRequestWithContext(ctx, dr.Method, u.String(), bytes.NewReader(dr.Body))
+	if err != nil {
+		return UpstreamResponse{}, err
+	}
+
 	// Make any required modifications to the outgoing request
-	for _, modifier := range h.modifiers.request {
-		if err = modifier.Modify(pv, req); err != nil {
-			return err
+	for _, modifier := range settings.modifiers.request {
+		_, span := tracing.Tracer().Start(ctx, fmt.Sprintf("%T", modifier))
+		err = modifier.Modify(pv, req)
+		tracing.End(span, err)
+		if err != nil {
+			return UpstreamResponse{}, err
 		}
 	}
 
 	// Copy any relevant downstream headers
-	for key, values := range c.Request().Header {
-		if shouldCopyHeader(key) {
+	for key, values := range dr.Header {
+		if shouldCopyHeader(pv, key) {
 			for i, value := range values {
 				if i == 0 {
 					// Set rather than add first value to ensure we overwrite any default values
@@ -86,40 +293,81 @@ func (h *Handler) handleForward(c echo.Context, pv provider.Provider) error {
 	}
 
 	// Add proxy headers
-	req.Header.Set("X-Forwarded-Proto", c.Request().Proto)
-	req.Header.Set("X-Forwarded-For", c.RealIP())
-	req.Header.Set("X-Real-IP", c.RealIP())
-
-	// Copy content length to avoid chunked encoding
-	req.ContentLength = c.Request().ContentLength
+	req.Header.Set("X-Forwarded-Proto", dr.Proto)
+	req.Header.Set("X-Forwarded-For", dr.RemoteIP)
+	req.Header.Set("X-Real-IP", dr.RemoteIP)
 
 	log.Debug().
 		Stringer(trace.LogProvider, pv).
-		Str("URI", c.Request().RequestURI).
+		Str("URI", req.URL.RequestURI()).
 		Msg("Forwarding request")
 
-	res, err := h.client.Do(req)
+	b := h.getBreaker(pv)
+	if b != nil && !b.Allow() {
+		return UpstreamResponse{}, fmt.Errorf("%w for provider %s", breaker.ErrOpen, pv)
+	}
+
+	_, span := tracing.Tracer().Start(
+		ctx,
+		fmt.Sprintf("%s %s", req.Method, metrics.Endpoint(dr.Path)),
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(
+			attribute.Stringer(trace.LogProvider, pv),
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.String()),
+		),
+	)
+	start := time.Now()
+	res, err := h.getClient(pv).Do(req)
+	status := metrics.UpstreamStatusError
+	if err == nil {
+		status = strconv.Itoa(res.StatusCode)
+		span.SetAttributes(semconv.HTTPResponseStatusCode(res.StatusCode))
+	}
+	metrics.UpstreamDuration.WithLabelValues(pv.String(), metrics.Endpoint(dr.Path), status).Observe(time.Since(start).Seconds())
+	tracing.End(span, err)
+
+	m.record(res, err)
+	if b != nil {
+		if err != nil || res.StatusCode >= http.StatusInternalServerError {
+			b.Failure()
+		} else {
+			b.Success()
+		}
+	}
 	if err != nil {
-		return err
+		return UpstreamResponse{}, err
 	}
 	defer func() { _ = res.Body.Close() }()
 
 	// Make any required modifications to the incoming response
-	for _, modifier := range h.modifiers.response {
-		if err = modifier.Modify(pv, res); err != nil {
-			return err
+	for _, modifier := range settings.modifiers.response {
+		_, span := tracing.Tracer().Start(ctx, fmt.Sprintf("%T", modifier))
+		err = modifier.Modify(pv, res)
+		tracing.End(span, err)
+		if err != nil {
+			return UpstreamResponse{}, err
 		}
 	}
 
-	bytes, err := io.ReadAll(res.Body)
+	content, err := io.ReadAll(res.Body)
 	if err != nil {
-		return err
+		return UpstreamResponse{}, err
 	}
 
-	return c.String(res.StatusCode, string(bytes))
+	return UpstreamResponse{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       content,
+	}, nil
 }
 
-func shouldCopyHeader(key string) bool {
+func (h *Handler) shouldFailover(p string) bool {
+	_, ok := h.failover.endpoints[strings.ToLower(path.Base(p))]
+	return ok
+}
+
+func shouldCopyHeader(pv provider.Provider, key string) bool {
 	// Keys *must* use canonical header format
 	switch key {
 	case "User-Agent":
@@ -129,7 +377,8 @@ func shouldCopyHeader(key string) bool {
 		return true
 	case "X-Bf2hub-Tsdata":
 		// Copy BF2Hub snapshot header (snapshots sent without are flagged and not processed)
-		return true
+		// Other providers do not know what to do with it, so only pass it on where it's used
+		return provider.SupportsTsdataHeader(pv)
 	default:
 		return false
 	}
@@ -4,107 +4,378 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
 
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/breaker"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/metrics"
 	"github.com/cetteup/playerpath/cmd/playerpath/internal/modify"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/tracing"
+	"github.com/cetteup/playerpath/internal/domain/archive"
 	"github.com/cetteup/playerpath/internal/domain/player"
 	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/domain/response"
+	"github.com/cetteup/playerpath/internal/domain/verification"
+	"github.com/cetteup/playerpath/internal/pkg/cache"
 	"github.com/cetteup/playerpath/internal/trace"
 )
 
 type Handler struct {
 	repository player.Repository
-	servers    map[string]provider.Provider
-	provider   provider.Provider
+	// settings Replaced as a whole on reload, so must only be accessed via getSettings
+	settings atomic.Pointer[settings]
 
-	modifiers struct {
-		request  []modify.RequestModifier
-		response []modify.ResponseModifier
+	client *http.Client
+
+	// upstreams Base URLs (and mirrors) to use instead of the providers' base URLs
+	upstreams map[provider.Provider][]mirror
+	// clients Clients for providers with their own transport settings, others use the shared client
+	clients map[provider.Provider]*http.Client
+
+	breakers struct {
+		sync.Mutex
+		threshold  int
+		cooldown   time.Duration
+		byProvider map[provider.Provider]*breaker.Breaker
 	}
 
-	client *http.Client
+	responses *cache.Cache[UpstreamResponse]
+	cacheTTLs map[string]CacheTTL
+
+	failover struct {
+		endpoints map[string]struct{}
+		timeout   time.Duration
+	}
+
+	fallback struct {
+		repository response.Repository
+		endpoints  map[string]struct{}
+		refresh    bool
+		// digests Digest of the response last persisted per player, provider and request
+		digests *cache.Cache[string]
+	}
+
+	// history Providers recently chosen for players using the same PID as others
+	history *cache.Cache[provider.Provider]
+
+	discovery playerDiscovery
+
+	verifications struct {
+		repository verification.Repository
+		providers  *cache.Cache[provider.Provider]
+	}
+
+	snapshots      archive.Repository
+	queue          snapshotQueue
+	splitSnapshots bool
+
+	debugHeaders bool
 }
 
 func NewHandler(repository player.Repository, servers map[string]provider.Provider, provider provider.Provider) *Handler {
-	return &Handler{
+	h := &Handler{
 		repository: repository,
-		servers:    servers,
-		provider:   provider,
-		client: &http.Client{
-			Transport: &http.Transport{
-				DisableCompression: true,
-			},
-			// Don't follow redirects, just return first response (mimic proxy behaviour)
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				return http.ErrUseLastResponse
-			},
-		},
+		history:    newHistory(),
 	}
+	// Client with default transport settings cannot fail to be created
+	h.client, _ = newClient(Transport{})
+	h.settings.Store(newSettings(servers, provider))
+	return h
+}
+
+// Reload Replace the configured servers, default provider and modifiers at once, e.g. after reloading the config.
+// Requests already being handled continue to use the previous settings.
+func (h *Handler) Reload(servers map[string]provider.Provider, provider provider.Provider, modifiers ...modify.Modifier) {
+	s := newSettings(servers, provider)
+	s.addModifiers(modifiers...)
+	h.settings.Store(s)
 }
 
 func (h *Handler) WithModifier(modifiers ...modify.Modifier) {
-	for _, modifier := range modifiers {
-		if modifier.Type() == modify.ModifierTypeRequest {
-			m, ok := modifier.(modify.RequestModifier)
-			if ok {
-				h.modifiers.request = append(h.modifiers.request, m)
-			}
-		}
+	s := h.getSettings().clone()
+	s.addModifiers(modifiers...)
+	h.settings.Store(s)
+}
 
-		if modifier.Type() == modify.ModifierTypeResponse {
-			m, ok := modifier.(modify.ResponseModifier)
-			if ok {
-				h.modifiers.response = append(h.modifiers.response, m)
-			}
-		}
+// WithCircuitBreaker Stop forwarding requests to a provider after the given number of consecutive failures,
+// only probing it again once the cooldown has passed
+func (h *Handler) WithCircuitBreaker(threshold int, cooldown time.Duration) {
+	h.breakers.threshold = threshold
+	h.breakers.cooldown = cooldown
+	h.breakers.byProvider = make(map[provider.Provider]*breaker.Breaker)
+}
+
+// WithResponseCache Cache successful responses for the given endpoints (e.g. getrankinfo.aspx) in memory,
+// keeping at most maxEntries responses
+func (h *Handler) WithResponseCache(endpoints map[string]CacheTTL, maxEntries int) {
+	h.responses = cache.New[UpstreamResponse](maxEntries)
+	h.cacheTTLs = make(map[string]CacheTTL, len(endpoints))
+	for endpoint, ttl := range endpoints {
+		h.cacheTTLs[strings.ToLower(path.Base(endpoint))] = ttl
+	}
+}
+
+// WithFailover Fail over to the server's and then the default provider if the player's provider fails to respond
+// with a valid ASP response to requests for any of the given endpoints (e.g. getunlocksinfo.aspx)
+func (h *Handler) WithFailover(endpoints []string, timeout time.Duration) {
+	h.failover.endpoints = make(map[string]struct{}, len(endpoints))
+	for _, endpoint := range endpoints {
+		h.failover.endpoints[strings.ToLower(path.Base(endpoint))] = struct{}{}
+	}
+	h.failover.timeout = timeout
+}
+
+// WithFallback Persist the last good response per player and provider for the given endpoints (e.g. getrankinfo.aspx),
+// serving it if the upstream fails to respond (with a refreshed asof timestamp, if refresh is true)
+func (h *Handler) WithFallback(repository response.Repository, endpoints []string, refresh bool) {
+	h.fallback.repository = repository
+	h.fallback.endpoints = make(map[string]struct{}, len(endpoints))
+	for _, endpoint := range endpoints {
+		h.fallback.endpoints[strings.ToLower(path.Base(endpoint))] = struct{}{}
 	}
+	h.fallback.refresh = refresh
+	h.fallback.digests = cache.New[string](fallbackDigestSize)
+}
+
+// WithPlayerDiscovery Probe the given providers for players which are not (yet) known, waiting at most timeout
+// for the providers to respond. Results are cached for the given ttl, found players are persisted.
+func (h *Handler) WithPlayerDiscovery(providers []provider.Provider, timeout time.Duration, ttl time.Duration) {
+	h.discovery.providers = providers
+	h.discovery.timeout = timeout
+	h.discovery.ttl = ttl
+	h.discovery.results = cache.New[[]player.Player](discoveryCacheSize)
 }
 
-func (h *Handler) determineProvider(ctx context.Context, pid int, serverIP string) (provider.Provider, error) {
-	// Primarily determine provider based on player
-	pv, err := h.getPlayerProvider(ctx, pid)
+// WithVerificationHistory Persist successful player verifications, preferring the verifying provider for any
+// subsequent requests for the player
+func (h *Handler) WithVerificationHistory(repository verification.Repository) {
+	h.verifications.repository = repository
+	h.verifications.providers = newVerificationCache()
+}
+
+// WithSnapshotArchive Persist every received snapshot along with the delivery status for each provider
+func (h *Handler) WithSnapshotArchive(repository archive.Repository) {
+	h.snapshots = repository
+}
+
+// WithSnapshotQueue Deliver archived snapshots in the background, retrying failed deliveries with exponential backoff
+// (starting at the given backoff) until the snapshot is older than maxAge. Requires a snapshot archive.
+func (h *Handler) WithSnapshotQueue(backoff time.Duration, maxAge time.Duration) {
+	h.queue = snapshotQueue{
+		backoff: backoff,
+		maxAge:  maxAge,
+	}
+}
+
+// WithSnapshotSplitting Send each provider a snapshot only containing its own players instead of the full snapshot
+func (h *Handler) WithSnapshotSplitting() {
+	h.splitSnapshots = true
+}
+
+// WithDebugHeaders Send the routing decision (provider, reason and candidate players) along with responses as X-Playerpath-* headers
+func (h *Handler) WithDebugHeaders() {
+	h.debugHeaders = true
+}
+
+// determineProvider Determine the provider to forward a player's request to. When inspecting (e.g. via the admin API),
+// the decision is made without side effects: players are not discovered, choices not remembered and metrics not recorded.
+func (h *Handler) determineProvider(
+	ctx context.Context,
+	pid int,
+	nick string,
+	serverIP string,
+	inspect bool,
+) (d decision, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "determineProvider", oteltrace.WithAttributes(
+		attribute.Int(trace.LogPlayerPID, pid),
+	))
+	defer func() {
+		tracing.End(span, err, attribute.Stringer(trace.LogProvider, d.Provider), attribute.String("reason", string(d.Reason)))
+	}()
+
+	// Manual overrides always take precedence
+	pv := h.getOverrideProvider(ctx, pid)
+	if pv != provider.Unknown {
+		return decision{Provider: pv, Reason: reasonOverride}, nil
+	}
+
+	// Prefer the provider which most recently verified the player, except for verification requests
+	// (the only ones including the nick), else a player who switched providers could never be verified again
+	if nick == "" {
+		pv, err = h.getVerifiedProvider(ctx, pid, serverIP)
+		if err != nil {
+			return decision{}, err
+		} else if pv != provider.Unknown {
+			return decision{Provider: pv, Reason: reasonVerification}, nil
+		}
+	}
+
+	// Otherwise determine provider based on player (using the server's provider to choose between players sharing the PID)
+	server := h.getSettings().servers[serverIP]
+	d, err = h.getPlayerProvider(ctx, pid, nick, server, inspect)
 	if err != nil {
-		return provider.Unknown, err
-	} else if pv != provider.Unknown {
-		return pv, nil
+		return decision{}, err
+	}
+
+	if len(d.Candidates) == 0 && !inspect {
+		// Player may be too new to have been imported yet
+		found, err2 := h.discoverPlayer(ctx, pid, serverIP)
+		if err2 != nil {
+			return decision{}, err2
+		}
+
+		d.Provider, d.Reason = h.choosePlayer(pid, nick, server, found, inspect)
+		d.Candidates = found
+		if d.Reason == reasonPlayer {
+			d.Reason = reasonDiscovery
+		}
+	}
+
+	if d.Provider != provider.Unknown {
+		return d, nil
 	}
 
 	// Alternative use server's default provider
-	pv = h.getServerProvider(serverIP)
-	if pv != provider.Unknown {
-		return pv, nil
+	d.Provider, d.Reason = h.getServerProvider(serverIP), reasonServer
+	if d.Provider != provider.Unknown {
+		return d, nil
 	}
 
 	// Finally fall back to overall default provider
-	return h.provider, nil
+	d.Provider, d.Reason = h.getSettings().provider, reasonDefault
+	return d, nil
 }
 
-func (h *Handler) getPlayerProvider(ctx context.Context, pid int) (provider.Provider, error) {
-	p, err := h.repository.FindByPID(ctx, pid)
-	if err != nil {
-		if errors.Is(err, player.ErrPlayerNotFound) {
+// getBreaker Get the provider's circuit breaker (nil if circuit breaking is disabled)
+func (h *Handler) getBreaker(pv provider.Provider) *breaker.Breaker {
+	if h.breakers.threshold <= 0 {
+		return nil
+	}
+
+	h.breakers.Lock()
+	defer h.breakers.Unlock()
+
+	b, ok := h.breakers.byProvider[pv]
+	if !ok {
+		b = breaker.New(h.breakers.threshold, h.breakers.cooldown, func(from, to breaker.State) {
 			log.Warn().
+				Stringer(trace.LogProvider, pv).
+				Stringer("from", from).
+				Stringer("to", to).
+				Msg("Circuit breaker state changed")
+		})
+		h.breakers.byProvider[pv] = b
+	}
+
+	return b
+}
+
+// determineFailoverProviders Determine the providers to try (in order) for a request, starting with the given provider
+func (h *Handler) determineFailoverProviders(pv provider.Provider, serverIP string) []provider.Provider {
+	providers := []provider.Provider{pv}
+	for _, alt := range []provider.Provider{h.getServerProvider(serverIP), h.getSettings().provider} {
+		if alt != provider.Unknown && !slices.Contains(providers, alt) {
+			providers = append(providers, alt)
+		}
+	}
+
+	return providers
+}
+
+// getOverrideProvider Get the provider the player has manually been pinned to (provider.Unknown if there is no override).
+// Failing to look up overrides (e.g. if the table has not been created yet) is logged, but does not fail the request,
+// as the provider can still be determined in other ways.
+func (h *Handler) getOverrideProvider(ctx context.Context, pid int) provider.Provider {
+	ctx, span := tracing.Tracer().Start(ctx, "FindOverrideByPID")
+	o, err := h.repository.FindOverrideByPID(ctx, pid)
+	tracing.End(span, ignore(err, player.ErrOverrideNotFound))
+	if err != nil {
+		if !errors.Is(err, player.ErrOverrideNotFound) {
+			log.Error().
+				Err(err).
 				Int(trace.LogPlayerPID, pid).
-				Msg("Player not found, deferring provider selection")
-			return provider.Unknown, nil
+				Msg("Failed to look up player override, ignoring overrides")
 		}
-		if errors.Is(err, player.ErrMultiplePlayersFound) {
+		return provider.Unknown
+	}
+
+	return o.Provider
+}
+
+// getPlayerProvider Determine the player's provider, using the nick (if known) and the server's provider (if known)
+// to choose between multiple players using the same PID. Chooses provider.Unknown if there is no such player
+// or none of the players can be chosen.
+func (h *Handler) getPlayerProvider(
+	ctx context.Context,
+	pid int,
+	nick string,
+	server provider.Provider,
+	inspect bool,
+) (decision, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "FindAllByPID")
+	candidates, err := h.repository.FindAllByPID(ctx, pid)
+	tracing.End(span, err, attribute.Int("candidates", len(candidates)))
+	if err != nil {
+		recordLookup(metrics.LookupOutcomeError, inspect)
+		return decision{}, err
+	}
+
+	switch len(candidates) {
+	case 0:
+		recordLookup(metrics.LookupOutcomeNotFound, inspect)
+		if !inspect {
 			log.Warn().
 				Int(trace.LogPlayerPID, pid).
-				Msg("Found multiple players, deferring provider selection")
-			return provider.Unknown, nil
+				Msg("Player not found, deferring provider selection")
 		}
-		return provider.Unknown, err
+	case 1:
+		recordLookup(metrics.LookupOutcomeFound, inspect)
+	default:
+		recordLookup(metrics.LookupOutcomeMultiple, inspect)
 	}
 
-	return p.Provider, nil
+	pv, r := h.choosePlayer(pid, nick, server, candidates, inspect)
+	return decision{
+		Provider:   pv,
+		Reason:     r,
+		Candidates: candidates,
+	}, nil
+}
+
+// recordLookup Count the player lookup, unless it was only made to inspect the decision
+func recordLookup(outcome string, inspect bool) {
+	if !inspect {
+		metrics.PlayerLookups.WithLabelValues(outcome).Inc()
+	}
 }
 
 func (h *Handler) getServerProvider(ip string) provider.Provider {
-	pv, ok := h.servers[ip]
+	return h.getSettings().getServerProvider(ip)
+}
+
+func (h *Handler) getServerOrDefaultProvider(ip string) (provider.Provider, reason) {
+	// Use a single snapshot of the settings, ensuring servers and default provider stem from the same config
+	s := h.getSettings()
+	pv := s.getServerProvider(ip)
+	if pv != provider.Unknown {
+		return pv, reasonServer
+	}
+
+	return s.provider, reasonDefault
+}
+
+func (s *settings) getServerProvider(ip string) provider.Provider {
+	pv, ok := s.servers[ip]
 	if !ok {
-		if len(h.servers) > 0 {
+		if len(s.servers) > 0 {
 			// Only log warning if any servers have been configured, which is totally optional
 			// (simple use cases work fine with just a default provider)
 			log.Warn().
@@ -117,11 +388,10 @@ func (h *Handler) getServerProvider(ip string) provider.Provider {
 	return pv
 }
 
-func (h *Handler) getServerOrDefaultProvider(ip string) provider.Provider {
-	pv := h.getServerProvider(ip)
-	if pv != provider.Unknown {
-		return pv
+// ignore Treat the target error as no error, e.g. for errors which are expected rather than a failure
+func ignore(err error, target error) error {
+	if errors.Is(err, target) {
+		return nil
 	}
-
-	return h.provider
+	return err
 }
@@ -0,0 +1,372 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/handler"
+	"github.com/cetteup/playerpath/internal/domain/player"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+const (
+	serverIP = "10.0.0.1"
+)
+
+type fakeRepository struct {
+	players   map[int][]player.Player
+	overrides map[int]player.Override
+	err       error
+}
+
+func (r *fakeRepository) UpsertMany(_ context.Context, players []player.Player) (int, error) {
+	return len(players), nil
+}
+
+func (r *fakeRepository) FindByPID(_ context.Context, _ int) (player.Player, error) {
+	return player.Player{}, errors.New("fakeRepository: handler should only use FindAllByPID")
+}
+
+func (r *fakeRepository) FindAllByPID(_ context.Context, pid int) ([]player.Player, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return append([]player.Player{}, r.players[pid]...), nil
+}
+
+func (r *fakeRepository) FindLastImported(_ context.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (r *fakeRepository) UpsertOverride(_ context.Context, o player.Override) error {
+	if r.overrides == nil {
+		r.overrides = map[int]player.Override{}
+	}
+	r.overrides[o.PID] = o
+	return nil
+}
+
+func (r *fakeRepository) DeleteOverride(_ context.Context, pid int) error {
+	delete(r.overrides, pid)
+	return nil
+}
+
+func (r *fakeRepository) FindOverrideByPID(_ context.Context, pid int) (player.Override, error) {
+	o, ok := r.overrides[pid]
+	if !ok {
+		return player.Override{}, player.ErrOverrideNotFound
+	}
+	return o, nil
+}
+
+func (r *fakeRepository) FindOverrides(_ context.Context) ([]player.Override, error) {
+	overrides := make([]player.Override, 0, len(r.overrides))
+	for _, o := range r.overrides {
+		overrides = append(overrides, o)
+	}
+	return overrides, nil
+}
+
+func TestHandler_DetermineProvider(t *testing.T) {
+	bf2hub := player.Player{PID: 45377286, Nick: "mister249", Provider: provider.BF2Hub}
+	playbf2 := player.Player{PID: 45377286, Nick: "Mister249", Provider: provider.PlayBF2}
+	openspy := player.Player{PID: 45377286, Nick: "someone", Provider: provider.OpenSpy}
+
+	tests := []struct {
+		name         string
+		givenPlayers []player.Player
+		givenErr     error
+		givenServers map[string]provider.Provider
+		override     provider.Provider
+		nick         string
+		serverIP     string
+		wantProvider provider.Provider
+		wantReason   string
+		wantErr      error
+	}{
+		{
+			name:         "chooses override over player",
+			givenPlayers: []player.Player{bf2hub},
+			override:     provider.OpenSpy,
+			wantProvider: provider.OpenSpy,
+			wantReason:   "override",
+		},
+		{
+			name:         "chooses only player using the pid",
+			givenPlayers: []player.Player{playbf2},
+			givenServers: map[string]provider.Provider{serverIP: provider.BF2Hub},
+			serverIP:     serverIP,
+			wantProvider: provider.PlayBF2,
+			wantReason:   "player",
+		},
+		{
+			name:         "chooses player by nick",
+			givenPlayers: []player.Player{bf2hub, openspy},
+			nick:         "someone",
+			wantProvider: provider.OpenSpy,
+			wantReason:   "nick",
+		},
+		{
+			name:         "chooses player by server provider",
+			givenPlayers: []player.Player{bf2hub, openspy},
+			givenServers: map[string]provider.Provider{serverIP: provider.OpenSpy},
+			serverIP:     serverIP,
+			wantProvider: provider.OpenSpy,
+			wantReason:   "server-player",
+		},
+		{
+			name:         "chooses server provider if players cannot be told apart",
+			givenPlayers: []player.Player{bf2hub, openspy},
+			givenServers: map[string]provider.Provider{serverIP: provider.PlayBF2},
+			serverIP:     serverIP,
+			wantProvider: provider.PlayBF2,
+			wantReason:   "server",
+		},
+		{
+			name:         "chooses server provider for unknown player",
+			givenServers: map[string]provider.Provider{serverIP: provider.OpenSpy},
+			serverIP:     serverIP,
+			wantProvider: provider.OpenSpy,
+			wantReason:   "server",
+		},
+		{
+			name:         "chooses default provider for unknown player on unknown server",
+			serverIP:     serverIP,
+			wantProvider: provider.BF2Hub,
+			wantReason:   "default",
+		},
+		{
+			name:     "fails if players cannot be looked up",
+			givenErr: errors.New("connection refused"),
+			wantErr:  errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			repository := &fakeRepository{
+				players:   map[int][]player.Player{45377286: tt.givenPlayers},
+				overrides: map[int]player.Override{},
+				err:       tt.givenErr,
+			}
+			if tt.override != provider.Unknown {
+				repository.overrides[45377286] = player.Override{PID: 45377286, Provider: tt.override}
+			}
+			h := handler.NewHandler(repository, tt.givenServers, provider.BF2Hub)
+
+			// WHEN
+			pv, r, err := h.DetermineProvider(context.Background(), 45377286, tt.nick, tt.serverIP, false)
+
+			// THEN
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantProvider, pv)
+			assert.Equal(t, tt.wantReason, r)
+		})
+	}
+}
+
+func TestHandler_DisambiguatePlayer(t *testing.T) {
+	bf2hub := player.Player{PID: 45377286, Nick: "mister249", Provider: provider.BF2Hub}
+	playbf2 := player.Player{PID: 45377286, Nick: "mister249", Provider: provider.PlayBF2}
+	openspy := player.Player{PID: 45377286, Nick: "someone", Provider: provider.OpenSpy}
+
+	tests := []struct {
+		name         string
+		givenNick    string
+		inspect      bool
+		candidates   []player.Player
+		nick         string
+		server       provider.Provider
+		wantProvider provider.Provider
+		wantReason   string
+	}{
+		{
+			name:         "chooses player by nick ignoring case",
+			candidates:   []player.Player{bf2hub, openspy},
+			nick:         "SOMEONE",
+			wantProvider: provider.OpenSpy,
+			wantReason:   "nick",
+		},
+		{
+			name:         "chooses player by server provider if nick matches multiple players",
+			candidates:   []player.Player{bf2hub, playbf2},
+			nick:         "mister249",
+			server:       provider.PlayBF2,
+			wantProvider: provider.PlayBF2,
+			wantReason:   "server-player",
+		},
+		{
+			name:         "chooses player previously chosen by nick",
+			givenNick:    "someone",
+			candidates:   []player.Player{bf2hub, openspy},
+			server:       provider.BF2Hub,
+			wantProvider: provider.OpenSpy,
+			wantReason:   "history",
+		},
+		{
+			name:         "does not remember players chosen by nick while inspecting",
+			givenNick:    "someone",
+			inspect:      true,
+			candidates:   []player.Player{bf2hub, openspy},
+			server:       provider.BF2Hub,
+			wantProvider: provider.BF2Hub,
+			wantReason:   "server-player",
+		},
+		{
+			name:         "chooses no player if none matches",
+			candidates:   []player.Player{bf2hub, openspy},
+			nick:         "nobody",
+			server:       provider.PlayBF2,
+			wantProvider: provider.Unknown,
+			wantReason:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			h := handler.NewHandler(&fakeRepository{}, nil, provider.BF2Hub)
+			if tt.givenNick != "" {
+				_, _ = h.DisambiguatePlayer(45377286, tt.givenNick, provider.Unknown, tt.candidates, tt.inspect)
+			}
+
+			// WHEN
+			pv, r := h.DisambiguatePlayer(45377286, tt.nick, tt.server, tt.candidates, tt.inspect)
+
+			// THEN
+			assert.Equal(t, tt.wantProvider, pv)
+			assert.Equal(t, tt.wantReason, r)
+		})
+	}
+}
+
+func TestHandler_PrepareSnapshotPayloads(t *testing.T) {
+	snapshot := `prefix\strike_at_karkand\pc\3\pID_0\1\name_0\a\pID_1\2\name_1\b\pID_2\3\name_2\c\EOF\1`
+
+	tests := []struct {
+		name            string
+		givenSplit      bool
+		givenOverrides  map[int]player.Override
+		givenErr        error
+		body            string
+		wantPayloads    map[provider.Provider]string
+		wantErrContains string
+	}{
+		{
+			name: "sends unaltered snapshot to every provider with players",
+			body: snapshot,
+			wantPayloads: map[provider.Provider]string{
+				provider.BF2Hub:  snapshot,
+				provider.PlayBF2: snapshot,
+			},
+		},
+		{
+			name:       "sends each provider only its own players",
+			givenSplit: true,
+			body:       snapshot,
+			wantPayloads: map[provider.Provider]string{
+				provider.BF2Hub:  `prefix\strike_at_karkand\pc\2\pID_0\1\name_0\a\pID_1\3\name_1\c\EOF\1`,
+				provider.PlayBF2: `prefix\strike_at_karkand\pc\1\pID_0\2\name_0\b\EOF\1`,
+			},
+		},
+		{
+			name:       "sends overridden players to override provider",
+			givenSplit: true,
+			givenOverrides: map[int]player.Override{
+				3: {PID: 3, Provider: provider.OpenSpy},
+			},
+			body: snapshot,
+			wantPayloads: map[provider.Provider]string{
+				provider.BF2Hub:  `prefix\strike_at_karkand\pc\1\pID_0\1\name_0\a\EOF\1`,
+				provider.PlayBF2: `prefix\strike_at_karkand\pc\1\pID_0\2\name_0\b\EOF\1`,
+				provider.OpenSpy: `prefix\strike_at_karkand\pc\1\pID_0\3\name_0\c\EOF\1`,
+			},
+		},
+		{
+			name:       "sends primary provider a snapshot without players",
+			givenSplit: true,
+			body:       `prefix\strike_at_karkand\pc\1\pID_0\2\name_0\b\EOF\1`,
+			wantPayloads: map[provider.Provider]string{
+				provider.BF2Hub:  `prefix\strike_at_karkand\pc\0\EOF\1`,
+				provider.PlayBF2: `prefix\strike_at_karkand\pc\1\pID_0\2\name_0\b\EOF\1`,
+			},
+		},
+		{
+			name: "sends snapshot with values which could not be decoded",
+			body: `prefix\strike_at_karkand\pc\1\pID_0\2\name_0\b\rs_0\1.5\EOF\1`,
+			wantPayloads: map[provider.Provider]string{
+				provider.BF2Hub:  `prefix\strike_at_karkand\pc\1\pID_0\2\name_0\b\rs_0\1.5\EOF\1`,
+				provider.PlayBF2: `prefix\strike_at_karkand\pc\1\pID_0\2\name_0\b\rs_0\1.5\EOF\1`,
+			},
+		},
+		{
+			name:            "fails for incomplete snapshot",
+			body:            `prefix\strike_at_karkand\pc\1\pID_0\1`,
+			wantErrContains: "missing EOF marker",
+		},
+		{
+			name:     "sends players which cannot be looked up to primary provider",
+			givenErr: errors.New("connection refused"),
+			body:     snapshot,
+			wantPayloads: map[provider.Provider]string{
+				provider.BF2Hub: snapshot,
+			},
+		},
+		{
+			name:       "sends only players which cannot be looked up to primary provider",
+			givenSplit: true,
+			givenErr:   errors.New("connection refused"),
+			givenOverrides: map[int]player.Override{
+				2: {PID: 2, Provider: provider.PlayBF2},
+			},
+			body: snapshot,
+			wantPayloads: map[provider.Provider]string{
+				provider.BF2Hub:  `prefix\strike_at_karkand\pc\2\pID_0\1\name_0\a\pID_1\3\name_1\c\EOF\1`,
+				provider.PlayBF2: `prefix\strike_at_karkand\pc\1\pID_0\2\name_0\b\EOF\1`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			repository := &fakeRepository{
+				players: map[int][]player.Player{
+					1: {{PID: 1, Nick: "a", Provider: provider.BF2Hub}},
+					2: {{PID: 2, Nick: "b", Provider: provider.PlayBF2}},
+				},
+				overrides: tt.givenOverrides,
+				err:       tt.givenErr,
+			}
+			h := handler.NewHandler(repository, nil, provider.BF2Hub)
+			if tt.givenSplit {
+				h.WithSnapshotSplitting()
+			}
+
+			// WHEN
+			payloads, err := h.PrepareSnapshotPayloads(context.Background(), []byte(tt.body), provider.BF2Hub)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				assert.ErrorContains(t, err, tt.wantErrContains)
+				return
+			}
+			require.NoError(t, err)
+			actual := make(map[provider.Provider]string, len(payloads))
+			for pv, payload := range payloads {
+				actual[pv] = string(payload)
+			}
+			assert.Equal(t, tt.wantPayloads, actual)
+		})
+	}
+}
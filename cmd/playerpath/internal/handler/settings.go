@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/modify"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+// settings Parts of the handler's configuration which can be replaced while serving requests. Never modified once
+// stored, but replaced as a whole (copy-on-write), so each request sees a consistent set of settings.
+type settings struct {
+	servers  map[string]provider.Provider
+	provider provider.Provider
+
+	modifiers struct {
+		request  []modify.RequestModifier
+		response []modify.ResponseModifier
+	}
+}
+
+func newSettings(servers map[string]provider.Provider, provider provider.Provider) *settings {
+	return &settings{
+		servers:  servers,
+		provider: provider,
+	}
+}
+
+func (h *Handler) getSettings() *settings {
+	return h.settings.Load()
+}
+
+func (s *settings) clone() *settings {
+	c := newSettings(maps.Clone(s.servers), s.provider)
+	c.modifiers.request = slices.Clone(s.modifiers.request)
+	c.modifiers.response = slices.Clone(s.modifiers.response)
+	return c
+}
+
+func (s *settings) addModifiers(modifiers ...modify.Modifier) {
+	for _, modifier := range modifiers {
+		if modifier.Type() == modify.ModifierTypeRequest {
+			m, ok := modifier.(modify.RequestModifier)
+			if ok {
+				s.modifiers.request = append(s.modifiers.request, m)
+			}
+		}
+
+		if modifier.Type() == modify.ModifierTypeResponse {
+			m, ok := modifier.(modify.ResponseModifier)
+			if ok {
+				s.modifiers.response = append(s.modifiers.response, m)
+			}
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/asp"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/snapshot"
+	"github.com/cetteup/playerpath/internal/domain/archive"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/trace"
+)
+
+const (
+	snapshotPath = "/ASP/sendsnapshot.aspx"
+
+	snapshotDeliveryTimeout = 30 * time.Second
+	snapshotMaxBackoff      = time.Hour
+	snapshotQueueBatchSize  = 50
+	snapshotPruneBatchSize  = 1000
+)
+
+type snapshotQueue struct {
+	backoff time.Duration
+	maxAge  time.Duration
+}
+
+func (q snapshotQueue) enabled() bool {
+	return q.backoff > 0
+}
+
+// backoffFor Determine the delay before the next attempt, doubling the initial backoff with every failed attempt
+func (q snapshotQueue) backoffFor(attempt int) time.Duration {
+	backoff := q.backoff
+	for i := 1; i < attempt && backoff < snapshotMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, snapshotMaxBackoff)
+}
+
+// HandleSnapshotForward Handle post-round snapshots, which are forwarded to every provider with players in the round.
+// If the snapshot was archived and queueing is enabled, deliveries are made in the background and the server receives
+// an OK response right away. Else, the response of the server's (or default) provider is passed back to the server.
+func (h *Handler) HandleSnapshotForward(c echo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return err
+	}
+
+	primary, r := h.getServerOrDefaultProvider(c.RealIP())
+	h.applyDecision(c, decision{Provider: primary, Reason: r})
+
+	ctx := c.Request().Context()
+	dr := newDownstreamRequest(c, body)
+	s := h.archiveSnapshot(ctx, dr)
+
+	payloads, err := h.prepareSnapshotPayloads(ctx, body, primary)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("ip", c.RealIP()).
+			Msg("Failed to determine snapshot providers, forwarding snapshot to server provider only")
+		payloads = map[provider.Provider][]byte{primary: body}
+	}
+
+	if h.queue.enabled() && s.ID != 0 {
+		h.enqueueSnapshot(ctx, s, dr, payloads)
+		return c.String(http.StatusOK, asp.NewOKResponse().WriteHeader("response").WriteData("OK").Serialize())
+	}
+
+	var wg sync.WaitGroup
+	for pv, payload := range payloads {
+		if pv == primary {
+			continue
+		}
+
+		wg.Go(func() {
+			_, _ = h.deliverSnapshot(ctx, s, pv, 1, withBody(dr, payload))
+		})
+	}
+
+	res, err := h.deliverSnapshot(ctx, s, primary, 1, withBody(dr, payloads[primary]))
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+
+	return c.String(res.StatusCode, string(res.Body))
+}
+
+// RunSnapshotQueue Periodically attempt any due deliveries of archived snapshots until the context is cancelled
+func (h *Handler) RunSnapshotQueue(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		deliveries, err := h.snapshots.FindDueDeliveries(ctx, time.Now().UTC(), snapshotQueueBatchSize)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Msg("Failed to find due snapshot deliveries")
+			continue
+		}
+
+		for _, d := range deliveries {
+			s, err2 := h.snapshots.FindByID(ctx, d.SnapshotID)
+			if err2 != nil {
+				log.Error().
+					Err(err2).
+					Int64("snapshot", d.SnapshotID).
+					Msg("Failed to load snapshot")
+				continue
+			}
+
+			dctx, cancel := context.WithTimeout(ctx, snapshotDeliveryTimeout)
+			_ = h.ReplaySnapshot(dctx, s, d)
+			cancel()
+		}
+	}
+}
+
+// RunSnapshotPruning Periodically delete archived snapshots older than the retention until the context is cancelled.
+// Snapshots with pending or failed deliveries are kept, so they can still be retried or replayed.
+func (h *Handler) RunSnapshotPruning(ctx context.Context, interval time.Duration, retention time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		before := time.Now().UTC().Add(-retention)
+		total := 0
+		for {
+			// Delete in batches, avoiding long-running deletes which would block archiving new snapshots
+			deleted, err := h.snapshots.DeleteDelivered(ctx, before, snapshotPruneBatchSize)
+			if err != nil {
+				log.Error().
+					Err(err).
+					Msg("Failed to prune archived snapshots")
+				break
+			}
+
+			total += deleted
+			if deleted < snapshotPruneBatchSize {
+				break
+			}
+		}
+
+		if total > 0 {
+			log.Info().
+				Int("snapshots", total).
+				Msg("Pruned archived snapshots")
+		}
+	}
+}
+
+// ReplaySnapshot Send an archived snapshot to the delivery's provider again
+func (h *Handler) ReplaySnapshot(ctx context.Context, s archive.Snapshot, d archive.Delivery) error {
+	payload := s.Data
+	if h.splitSnapshots {
+		primary, _ := h.getServerOrDefaultProvider(s.ServerIP)
+		payloads, err := h.prepareSnapshotPayloads(ctx, s.Data, primary)
+		if err != nil {
+			return err
+		}
+
+		var ok bool
+		if payload, ok = payloads[d.Provider]; !ok {
+			// Retrying is pointless, since the provider no longer has any players in the snapshot
+			err = fmt.Errorf("snapshot contains no players for provider %s", d.Provider)
+			d.Status = archive.StatusFailed
+			d.Error = err.Error()
+			d.Updated = time.Now().UTC()
+			h.recordDelivery(ctx, d)
+			return err
+		}
+	}
+
+	dr := downstreamRequest{
+		Method:   http.MethodPost,
+		Path:     snapshotPath,
+		Proto:    "HTTP/1.1",
+		Header:   s.Header,
+		RemoteIP: s.ServerIP,
+		Body:     payload,
+	}
+
+	res, err := h.deliverSnapshot(ctx, s, d.Provider, d.Attempts+1, dr)
+	return checkSnapshotResponse(res, err)
+}
+
+// prepareSnapshotPayloads Determine the snapshot payload to send to each provider with players in the round.
+// Players whose provider cannot be determined are attributed to the primary provider, which always receives a payload.
+// Unless snapshot splitting is enabled, every provider receives the unaltered snapshot.
+func (h *Handler) prepareSnapshotPayloads(
+	ctx context.Context,
+	body []byte,
+	primary provider.Provider,
+) (map[provider.Provider][]byte, error) {
+	s, err := snapshot.Parse(string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.Validate(); err != nil {
+		return nil, err
+	}
+	if len(s.Warnings) > 0 {
+		// Values which cannot be decoded are passed on as is, leaving it up to the providers to handle them
+		log.Warn().
+			Errs("warnings", s.Warnings).
+			Str("map", s.MapName).
+			Msg("Snapshot contains values which could not be decoded")
+	}
+
+	providers := []provider.Provider{primary}
+	assignments := make(map[int]provider.Provider, len(s.Players))
+	for _, p := range s.Players {
+		pv := h.getOverrideProvider(ctx, p.PID)
+		if pv == provider.Unknown {
+			d, err3 := h.getPlayerProvider(ctx, p.PID, p.Name, primary, false)
+			if err3 != nil {
+				// Don't hold back the other players' stats, send this player's to the server provider instead
+				log.Error().
+					Err(err3).
+					Int(trace.LogPlayerPID, p.PID).
+					Stringer(trace.LogProvider, primary).
+					Msg("Failed to determine snapshot player provider, sending player to server provider")
+			}
+			pv = d.Provider
+		}
+
+		if pv == provider.Unknown {
+			pv = primary
+		}
+
+		assignments[p.Index] = pv
+		if !slices.Contains(providers, pv) {
+			providers = append(providers, pv)
+		}
+	}
+
+	payloads := make(map[provider.Provider][]byte, len(providers))
+	for _, pv := range providers {
+		if !h.splitSnapshots {
+			payloads[pv] = body
+			continue
+		}
+
+		// Only send the round plus the provider's own players, since other players are unknown to the provider
+		payloads[pv] = []byte(s.Filter(func(p snapshot.Player) bool {
+			return assignments[p.Index] == pv
+		}).Serialize())
+	}
+
+	return payloads, nil
+}
+
+// archiveSnapshot Persist the snapshot as received (ID remains 0 if the snapshot was not archived)
+func (h *Handler) archiveSnapshot(ctx context.Context, dr downstreamRequest) archive.Snapshot {
+	s := archive.Snapshot{
+		ServerIP: dr.RemoteIP,
+		Header:   dr.Header.Clone(),
+		Data:     dr.Body,
+		Received: time.Now().UTC(),
+	}
+
+	if h.snapshots == nil {
+		return s
+	}
+
+	id, err := h.snapshots.Insert(ctx, s)
+	if err != nil {
+		// Failing to archive the snapshot should not prevent it from being forwarded
+		log.Error().
+			Err(err).
+			Str("ip", dr.RemoteIP).
+			Msg("Failed to archive snapshot")
+		return s
+	}
+
+	s.ID = id
+	return s
+}
+
+// enqueueSnapshot Record pending deliveries for the snapshot and immediately attempt them in the background
+func (h *Handler) enqueueSnapshot(
+	ctx context.Context,
+	s archive.Snapshot,
+	dr downstreamRequest,
+	payloads map[provider.Provider][]byte,
+) {
+	// Detach from the downstream request, which will have been completed long before the deliveries
+	ctx = context.WithoutCancel(ctx)
+	for pv, payload := range payloads {
+		now := time.Now().UTC()
+		h.recordDelivery(ctx, archive.Delivery{
+			SnapshotID: s.ID,
+			Provider:   pv,
+			Status:     archive.StatusPending,
+			// Ensure the queue does not pick up the delivery while the initial attempt is still in progress
+			NextAttempt: now.Add(snapshotDeliveryTimeout),
+			Updated:     now,
+		})
+
+		go func() {
+			dctx, cancel := context.WithTimeout(ctx, snapshotDeliveryTimeout)
+			defer cancel()
+			_, _ = h.deliverSnapshot(dctx, s, pv, 1, withBody(dr, payload))
+		}()
+	}
+}
+
+func (h *Handler) deliverSnapshot(
+	ctx context.Context,
+	s archive.Snapshot,
+	pv provider.Provider,
+	attempt int,
+	dr downstreamRequest,
+) (UpstreamResponse, error) {
+	res, err := h.forward(ctx, pv, dr)
+	failure := checkSnapshotResponse(res, err)
+	if failure != nil {
+		log.Error().
+			Err(failure).
+			Stringer(trace.LogProvider, pv).
+			Int64("snapshot", s.ID).
+			Int("attempt", attempt).
+			Msg("Failed to deliver snapshot")
+	} else {
+		log.Debug().
+			Stringer(trace.LogProvider, pv).
+			Int64("snapshot", s.ID).
+			Int("attempt", attempt).
+			Msg("Delivered snapshot")
+	}
+
+	if s.ID == 0 {
+		return res, err
+	}
+
+	now := time.Now().UTC()
+	d := archive.Delivery{
+		SnapshotID: s.ID,
+		Provider:   pv,
+		Status:     archive.StatusDelivered,
+		StatusCode: res.StatusCode,
+		Attempts:   attempt,
+		Updated:    now,
+	}
+	if failure != nil {
+		d.Error = failure.Error()
+		d.NextAttempt = now.Add(h.queue.backoffFor(attempt))
+		if h.queue.enabled() && d.NextAttempt.Sub(s.Received) < h.queue.maxAge {
+			d.Status = archive.StatusPending
+		} else {
+			d.Status = archive.StatusFailed
+		}
+	}
+
+	// Record delivery even if the downstream request has been cancelled in the meantime
+	h.recordDelivery(context.WithoutCancel(ctx), d)
+
+	return res, err
+}
+
+func (h *Handler) recordDelivery(ctx context.Context, d archive.Delivery) {
+	if err := h.snapshots.UpsertDelivery(ctx, d); err != nil {
+		log.Error().
+			Err(err).
+			Stringer(trace.LogProvider, d.Provider).
+			Int64("snapshot", d.SnapshotID).
+			Msg("Failed to record snapshot delivery")
+	}
+}
+
+// checkSnapshotResponse Determine whether the snapshot delivery failed, either due to an error or the snapshot being rejected
+func checkSnapshotResponse(res UpstreamResponse, err error) error {
+	if err != nil {
+		return err
+	}
+
+	// ASP always responds 200/OK, an accepted snapshot is indicated by an OK response type
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(string(res.Body), "O\n") {
+		return fmt.Errorf("unexpected response: %q", res.Body)
+	}
+
+	return nil
+}
+
+func withBody(dr downstreamRequest, body []byte) downstreamRequest {
+	dr.Body = body
+	return dr
+}
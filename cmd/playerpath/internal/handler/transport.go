@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Transport Settings for the HTTP connections to a provider, zero values meaning Go's defaults
+type Transport struct {
+	// DialTimeout Time to wait for a connection to be established
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout Time to wait for the TLS handshake
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout Time to wait for the response headers after sending the request
+	ResponseHeaderTimeout time.Duration
+	// MaxIdleConns Maximum number of idle (keep-alive) connections to keep open
+	MaxIdleConns int
+	// Proxy URL of an HTTP(S) or SOCKS5 proxy to send requests through (e.g. socks5://127.0.0.1:1080)
+	Proxy string
+	// CAFile Path to a PEM bundle of CA certificates to trust in addition to the system's
+	CAFile string
+}
+
+// newClient Create a client for forwarding requests, using the given transport settings
+func newClient(t Transport) (*http.Client, error) {
+	dialer := &net.Dialer{
+		Timeout:   t.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   t.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: t.ResponseHeaderTimeout,
+		MaxIdleConns:          t.MaxIdleConns,
+		MaxIdleConnsPerHost:   t.MaxIdleConns,
+		IdleConnTimeout:       90 * time.Second,
+		DisableCompression:    true,
+	}
+
+	if t.Proxy != "" {
+		u, err := url.Parse(t.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme: %s", u.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	if t.CAFile != "" {
+		pool, err := loadCertPool(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		// Don't follow redirects, just return first response (mimic proxy behaviour)
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, nil
+}
+
+// loadCertPool Load the system's CA certificates, adding those from the given PEM bundle
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, errors.New("no valid certificates found in CA bundle: " + path)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/trace"
+)
+
+const (
+	defaultMirrorThreshold = 3
+	defaultMirrorCooldown  = 30 * time.Second
+)
+
+// Upstream Where to forward a provider's requests to, overriding the provider's base URL
+type Upstream struct {
+	// URL Base URL to use instead of the provider's (uses the provider's current base URL if empty)
+	URL string
+	// Mirrors Alternative base URLs to use (in order) while the preceding ones are unhealthy
+	Mirrors []string
+	// Threshold Number of consecutive failures after which a URL is considered unhealthy
+	Threshold int
+	// Cooldown Time after which an unhealthy URL is tried again
+	Cooldown time.Duration
+	// Transport Settings for the connections to the provider (uses the shared client if nil)
+	Transport *Transport
+}
+
+// mirror Base URL of a provider along with its health (nil if the provider has no mirrors)
+type mirror struct {
+	// url Base URL to forward requests to (nil to use the provider's current base URL)
+	url    *url.URL
+	health *health
+}
+
+// record Record the outcome of a request sent to the mirror
+func (m mirror) record(res *http.Response, err error) {
+	if m.health == nil {
+		return
+	}
+
+	if err != nil || res.StatusCode >= http.StatusInternalServerError {
+		m.health.failure()
+	} else {
+		m.health.success()
+	}
+}
+
+// health Tracks consecutive failures of a URL, considering it unhealthy for a cooldown once the threshold is reached.
+// Unlike a circuit breaker, it never blocks requests but merely informs which URL to prefer.
+type health struct {
+	threshold int
+	cooldown  time.Duration
+	onChange  func(healthy bool)
+
+	mu       sync.Mutex
+	failures int
+	retry    time.Time
+}
+
+func (h *health) healthy() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.failures < h.threshold || !time.Now().Before(h.retry)
+}
+
+func (h *health) success() {
+	h.mu.Lock()
+	recovered := h.failures >= h.threshold
+	h.failures = 0
+	h.mu.Unlock()
+
+	if recovered {
+		h.onChange(true)
+	}
+}
+
+func (h *health) failure() {
+	h.mu.Lock()
+	h.failures++
+	failed := h.failures == h.threshold
+	if h.failures >= h.threshold {
+		h.retry = time.Now().Add(h.cooldown)
+	}
+	h.mu.Unlock()
+
+	if failed {
+		h.onChange(false)
+	}
+}
+
+// WithUpstreams Override the base URL and transport settings of providers, optionally adding mirrors which are used
+// while the base URL is unhealthy
+func (h *Handler) WithUpstreams(upstreams map[provider.Provider]Upstream) error {
+	h.upstreams = make(map[provider.Provider][]mirror, len(upstreams))
+	h.clients = make(map[provider.Provider]*http.Client)
+	for pv, upstream := range upstreams {
+		if upstream.Transport != nil {
+			client, err := newClient(*upstream.Transport)
+			if err != nil {
+				return fmt.Errorf("invalid transport for provider %s: %w", pv, err)
+			}
+			h.clients[pv] = client
+		}
+
+		// Without a URL, the provider's base URL is resolved per request, so reloading the catalogue takes effect
+		urls := append([]string{upstream.URL}, upstream.Mirrors...)
+		mirrors := make([]mirror, 0, len(urls))
+		for i, raw := range urls {
+			var m mirror
+			if i > 0 || raw != "" {
+				u, err := url.Parse(raw)
+				if err != nil {
+					return fmt.Errorf("invalid upstream url for provider %s: %w", pv, err)
+				}
+				if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+					return fmt.Errorf("invalid upstream url for provider %s: %s", pv, raw)
+				}
+				m.url = u
+			}
+
+			// Health only matters if there are alternatives to choose from
+			if len(urls) > 1 {
+				m.health = &health{
+					threshold: cmp.Or(upstream.Threshold, defaultMirrorThreshold),
+					cooldown:  cmp.Or(upstream.Cooldown, defaultMirrorCooldown),
+					onChange: func(healthy bool) {
+						log.Warn().
+							Stringer(trace.LogProvider, pv).
+							Str("url", cmp.Or(raw, provider.GetBaseURL(pv))).
+							Bool("healthy", healthy).
+							Msg("Upstream health changed")
+					},
+				}
+			}
+			mirrors = append(mirrors, m)
+		}
+		h.upstreams[pv] = mirrors
+	}
+
+	return nil
+}
+
+// selectUpstream Select the base URL to forward the provider's requests to, preferring the first healthy URL
+func (h *Handler) selectUpstream(pv provider.Provider) (mirror, error) {
+	mirrors, ok := h.upstreams[pv]
+	if !ok {
+		return resolveMirror(pv, mirror{})
+	}
+
+	for _, m := range mirrors {
+		if m.health == nil || m.health.healthy() {
+			return resolveMirror(pv, m)
+		}
+	}
+
+	// With all URLs unhealthy, use the primary URL anyway (the provider's circuit breaker guards against outages)
+	return resolveMirror(pv, mirrors[0])
+}
+
+// resolveMirror Resolve the provider's current base URL for the mirror, unless it has a URL of its own
+func resolveMirror(pv provider.Provider, m mirror) (mirror, error) {
+	if m.url != nil {
+		return m, nil
+	}
+
+	u, err := url.Parse(provider.GetBaseURL(pv))
+	if err != nil {
+		return mirror{}, err
+	}
+	m.url = u
+	return m, nil
+}
+
+// getClient Get the client to forward the provider's requests with
+func (h *Handler) getClient(pv provider.Provider) *http.Client {
+	if client, ok := h.clients[pv]; ok {
+		return client
+	}
+	return h.client
+}
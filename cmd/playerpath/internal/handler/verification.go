@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/asp"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/tracing"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/domain/verification"
+	"github.com/cetteup/playerpath/internal/pkg/cache"
+	"github.com/cetteup/playerpath/internal/trace"
+)
+
+const (
+	verificationCacheTTL  = 5 * time.Minute
+	verificationCacheSize = 10000
+	verificationTimeout   = 5 * time.Second
+)
+
+func newVerificationCache() *cache.Cache[provider.Provider] {
+	return cache.New[provider.Provider](verificationCacheSize)
+}
+
+// getVerifiedProvider Get the provider which most recently verified a player using the PID on the given server,
+// or on any server if the server is not known (provider.Unknown if the player has not been verified)
+func (h *Handler) getVerifiedProvider(ctx context.Context, pid int, serverIP string) (provider.Provider, error) {
+	if h.verifications.repository == nil {
+		return provider.Unknown, nil
+	}
+
+	// Cache any result (including no verification), since every request would otherwise hit the database
+	key := getVerificationCacheKey(pid, serverIP)
+	if pv, freshness := h.verifications.providers.Get(key); freshness == cache.Fresh {
+		return pv, nil
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "FindLatestVerification")
+	var v verification.Verification
+	var err error
+	if serverIP != "" {
+		// Players sharing a PID may be verified by different providers on different servers
+		v, err = h.verifications.repository.FindLatestByPIDAndServer(ctx, pid, serverIP)
+	} else {
+		v, err = h.verifications.repository.FindLatestByPID(ctx, pid)
+	}
+	tracing.End(span, ignore(err, verification.ErrVerificationNotFound))
+	if err != nil && !errors.Is(err, verification.ErrVerificationNotFound) {
+		return provider.Unknown, err
+	}
+
+	h.verifications.providers.Set(key, v.Provider, verificationCacheTTL, 0)
+
+	return v.Provider, nil
+}
+
+// recordVerification Persist the verification in the background, provided the provider successfully verified the player
+func (h *Handler) recordVerification(
+	ctx context.Context,
+	pid int,
+	nick string,
+	pv provider.Provider,
+	serverIP string,
+	res UpstreamResponse,
+) {
+	if h.verifications.repository == nil || res.StatusCode != http.StatusOK {
+		return
+	}
+
+	r, err := asp.Parse(string(res.Body))
+	if err != nil || !r.OK() {
+		return
+	}
+
+	if result, ok := r.Get("result"); !ok || result != "Ok" {
+		return
+	}
+
+	// Subsequent requests for the player's rank, unlocks etc. usually follow right away
+	h.verifications.providers.Set(getVerificationCacheKey(pid, serverIP), pv, verificationCacheTTL, 0)
+	// Lookups without a server consider verifications on any server, including this one
+	h.verifications.providers.Delete(getVerificationCacheKey(pid, ""))
+
+	// Downstream request will have been completed by the time the verification is persisted
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, verificationTimeout)
+		defer cancel()
+
+		err := h.verifications.repository.Upsert(ctx, verification.Verification{
+			PID:      pid,
+			Nick:     nick,
+			Provider: pv,
+			ServerIP: serverIP,
+			Verified: time.Now().UTC(),
+		})
+		if err != nil {
+			log.Error().
+				Err(err).
+				Int(trace.LogPlayerPID, pid).
+				Str(trace.LogPlayerNick, nick).
+				Stringer(trace.LogProvider, pv).
+				Msg("Failed to persist player verification")
+		}
+	}()
+}
+
+func getVerificationCacheKey(pid int, serverIP string) string {
+	return strconv.Itoa(pid) + "|" + serverIP
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"path"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	namespace = "playerpath"
+
+	LookupOutcomeFound    = "found"
+	LookupOutcomeNotFound = "not_found"
+	LookupOutcomeMultiple = "multiple"
+	LookupOutcomeError    = "error"
+
+	UpstreamStatusError = "error"
+
+	endpointOther = "other"
+)
+
+var (
+	Requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Number of forwarded requests by endpoint, chosen provider and reason the provider was chosen",
+	}, []string{"endpoint", "provider", "reason"})
+
+	UpstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Duration of requests to providers by provider, endpoint and response status (error if no response was received)",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2, 3, 5, 10},
+	}, []string{"provider", "endpoint", "status"})
+
+	PlayerLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "player_lookups_total",
+		Help:      "Number of player lookups by outcome (found, not_found, multiple, error)",
+	}, []string{"outcome"})
+
+	// endpoints Known ASP endpoints, any other paths are counted as "other" to keep label cardinality bounded
+	endpoints = map[string]struct{}{
+		"getplayerinfo.aspx":    {},
+		"getawardsinfo.aspx":    {},
+		"getunlocksinfo.aspx":   {},
+		"getrankinfo.aspx":      {},
+		"verifyplayer.aspx":     {},
+		"sendsnapshot.aspx":     {},
+		"getrankstatus.aspx":    {},
+		"getbackendinfo.aspx":   {},
+		"getleaderboard.aspx":   {},
+		"searchforplayers.aspx": {},
+		"selectunlock.aspx":     {},
+		"ranknotification.aspx": {},
+		"getclaninfo.aspx":      {},
+	}
+)
+
+// Endpoint Get the endpoint label value for the request path
+func Endpoint(p string) string {
+	endpoint := strings.ToLower(path.Base(p))
+	if _, ok := endpoints[endpoint]; !ok {
+		return endpointOther
+	}
+
+	return endpoint
+}
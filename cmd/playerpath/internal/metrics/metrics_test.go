@@ -0,0 +1,43 @@
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/metrics"
+)
+
+func TestEndpoint(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		wantEndpoint string
+	}{
+		{
+			name:         "returns known endpoint",
+			path:         "/ASP/getrankinfo.aspx",
+			wantEndpoint: "getrankinfo.aspx",
+		},
+		{
+			name:         "returns known endpoint in lower case",
+			path:         "/ASP/VerifyPlayer.aspx",
+			wantEndpoint: "verifyplayer.aspx",
+		},
+		{
+			name:         "returns other for unknown endpoint",
+			path:         "/ASP/doesnotexist.aspx",
+			wantEndpoint: "other",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			endpoint := metrics.Endpoint(tt.path)
+
+			// THEN
+			assert.Equal(t, tt.wantEndpoint, endpoint)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package modify
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net/http"
@@ -8,8 +9,11 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/rs/zerolog/log"
+
 	"github.com/cetteup/playerpath/cmd/playerpath/internal/asp"
 	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/trace"
 )
 
 const (
@@ -32,7 +36,7 @@ func (m VerificationResponseModifier) Modify(pv provider.Provider, res *http.Res
 		return err
 	}
 
-	q := parseVerifyPlayersQuery(res.Request.URL.RawQuery)
+	q := ParseVerifyPlayersQuery(res.Request.URL.RawQuery)
 	pid := q.Get("pid")
 	nick := q.Get("SoldierNick")
 
@@ -41,8 +45,13 @@ func (m VerificationResponseModifier) Modify(pv provider.Provider, res *http.Res
 		return nil
 	}
 
-	if pv == provider.BF2Hub {
-		resp, err2 := transformBF2HubPlayerVerificationResult(pid, nick, string(body))
+	if provider.UsesBF2HubPlayerVerification(pv) {
+		result, err2 := asp.Parse(string(body))
+		if err2 != nil {
+			return err2
+		}
+
+		resp, err2 := transformBF2HubPlayerVerificationResult(pid, nick, result)
 		if err2 != nil {
 			return err2
 		}
@@ -73,8 +82,8 @@ func (m VerificationResponseModifier) skip(pv provider.Provider, res *http.Respo
 	return false
 }
 
-// parseVerifyPlayersQuery Query string parsing specifically for expected parameters of VerifyPlayers.aspx
-func parseVerifyPlayersQuery(query string) url.Values {
+// ParseVerifyPlayersQuery Query string parsing specifically for expected parameters of VerifyPlayers.aspx
+func ParseVerifyPlayersQuery(query string) url.Values {
 	q := make(url.Values)
 
 	// Battlefield 2 does not query encode any characters whatsoever, not even ones that can be mistaken for syntax
@@ -109,17 +118,23 @@ func parseVerifyPlayersQuery(query string) url.Values {
 	return q
 }
 
-func transformBF2HubPlayerVerificationResult(pid, nick, result string) (*asp.Response, error) {
+func transformBF2HubPlayerVerificationResult(pid, nick string, result *asp.Response) (*asp.Response, error) {
+	// BF2Hub indicates the verification result via the error code
+	code, ok := result.ErrorCode()
+	if !ok {
+		return nil, fmt.Errorf("unexpected player verification response: %s", result.Serialize())
+	}
+
 	var valid bool
-	switch result {
-	case "E\t996\n$\t4\t$":
+	switch code {
+	case 996:
 		valid = true
-	case "E\t997\n$\t4\t$":
+	case 997:
 		valid = false
-	case "E\t999\n$\t4\t$":
+	case 999:
 		return asp.NewSyntaxErrorResponse(), nil
 	default:
-		return nil, fmt.Errorf("unknown player verification response code: %s", result)
+		return nil, fmt.Errorf("unknown player verification response code: %d", code)
 	}
 
 	resp := asp.NewOKResponse().
@@ -142,6 +157,40 @@ func transformBF2HubPlayerVerificationResult(pid, nick, result string) (*asp.Res
 	return resp, nil
 }
 
+// ValidationResponseModifier Replaces any response not conforming to the ASP response format
+// (e.g. HTML error pages, truncated bodies) with a well-formed error response
+type ValidationResponseModifier struct{}
+
+func (m ValidationResponseModifier) Type() ModifierType {
+	return ModifierTypeResponse
+}
+
+func (m ValidationResponseModifier) Modify(pv provider.Provider, res *http.Response) error {
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if _, err = asp.Parse(string(body)); err != nil {
+		log.Warn().
+			Err(err).
+			Stringer(trace.LogProvider, pv).
+			Str("path", res.Request.URL.Path).
+			Int("status", res.StatusCode).
+			Msg("Replacing invalid upstream response")
+
+		// ASP should *always* respond 200/OK, even for errors
+		res.StatusCode = http.StatusOK
+		res.Status = fmt.Sprintf("%d %s", http.StatusOK, http.StatusText(http.StatusOK))
+		body = []byte(asp.NewUpstreamErrorResponse().Serialize())
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+
+	return nil
+}
+
 func addInvalidPrefix(nick string) string {
 	// `[prefix] nick` usually get cut off after 23 characters in the game's client-server protocols
 	// While the limit appears to not be applied to values returned by the validation,
@@ -130,6 +130,22 @@ func TestVerificationResponseModifier_Modify(t *testing.T) {
 			},
 			wantErrContains: "unknown player verification response code",
 		},
+		{
+			name:     "fails for non-error response from BF2Hub",
+			provider: provider.BF2Hub,
+			prepare: func(res *http.Response) {
+				res.Body = io.NopCloser(strings.NewReader("O\n$\t1\t$"))
+			},
+			wantErrContains: "unexpected player verification response",
+		},
+		{
+			name:     "fails for malformed response from BF2Hub",
+			provider: provider.BF2Hub,
+			prepare: func(res *http.Response) {
+				res.Body = io.NopCloser(strings.NewReader("<html>Bad Gateway</html>"))
+			},
+			wantErrContains: "invalid ASP response",
+		},
 	}
 
 	// Mock time.Now in asp package
@@ -168,6 +184,79 @@ func TestVerificationResponseModifier_Modify(t *testing.T) {
 	}
 }
 
+func TestValidationResponseModifier_Modify(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "passes through valid OK response",
+			status:     http.StatusOK,
+			body:       "O\nH\tresult\nD\tOk\n$\t11\t$",
+			wantStatus: http.StatusOK,
+			wantBody:   "O\nH\tresult\nD\tOk\n$\t11\t$",
+		},
+		{
+			name:       "passes through valid error response",
+			status:     http.StatusOK,
+			body:       "E\t996\n$\t4\t$",
+			wantStatus: http.StatusOK,
+			wantBody:   "E\t996\n$\t4\t$",
+		},
+		{
+			name:       "replaces HTML error page",
+			status:     http.StatusBadGateway,
+			body:       "<html><body>Bad Gateway</body></html>",
+			wantStatus: http.StatusOK,
+			wantBody:   "E\t999\nH\tasof\terr\nD\t1771369200\tUpstream Error!\n$\t38\t$",
+		},
+		{
+			name:       "replaces truncated response",
+			status:     http.StatusOK,
+			body:       "O\nH\tpid\tnick\nD\t1234567890\twalt",
+			wantStatus: http.StatusOK,
+			wantBody:   "E\t999\nH\tasof\terr\nD\t1771369200\tUpstream Error!\n$\t38\t$",
+		},
+		{
+			name:       "replaces response with wrong size",
+			status:     http.StatusOK,
+			body:       "O\nH\tresult\nD\tOk\n$\t4\t$",
+			wantStatus: http.StatusOK,
+			wantBody:   "E\t999\nH\tasof\terr\nD\t1771369200\tUpstream Error!\n$\t38\t$",
+		},
+	}
+
+	// Mock time.Now in asp package
+	var now = time.Date(2026, 2, 17, 23, 0, 0, 0, time.UTC)
+	asp.Now = func() time.Time { return now }
+	t.Cleanup(func() { asp.Now = time.Now })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			modifier := modify.ValidationResponseModifier{}
+			res := givenResponse("/ASP/getrankinfo.aspx", "pid=1234567890", tt.status, tt.body)
+
+			// WHEN
+			err := modifier.Modify(provider.BF2Hub, res)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			if tt.status != tt.wantStatus {
+				assert.Equal(t, "200 OK", res.Status)
+			}
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(body))
+			assert.Equal(t, int64(len(tt.wantBody)), res.ContentLength)
+		})
+	}
+}
+
 func givenResponse(path string, query string, status int, body string) *http.Response {
 	return &http.Response{
 		Status:        http.StatusText(status),
@@ -2,6 +2,8 @@ package options
 
 import (
 	"flag"
+	"os"
+	"time"
 
 	"github.com/cetteup/playerpath/internal/domain/provider"
 )
@@ -10,12 +12,24 @@ type Options struct {
 	Version bool
 
 	ListenAddr   string
+	MetricsAddr  string
 	Debug        bool
 	ColorizeLogs bool
 
 	ConfigPath string
+	ConfigPoll time.Duration
 
-	Provider provider.Provider
+	// Provider Name of the default provider, which can only be resolved once any custom providers have been loaded
+	Provider string
+
+	ValidateResponses bool
+
+	SplitSnapshots    bool
+	SnapshotBackoff   time.Duration
+	SnapshotMaxAge    time.Duration
+	SnapshotRetention time.Duration
+
+	DebugHeaders bool
 }
 
 func Init() *Options {
@@ -25,8 +39,52 @@ func Init() *Options {
 	flag.BoolVar(&opts.Debug, "debug", false, "enable debug logging")
 	flag.BoolVar(&opts.ColorizeLogs, "colorize-logs", false, "colorize log messages")
 	flag.StringVar(&opts.ListenAddr, "address", ":8080", "server/bind address in format [host]:port")
+	flag.StringVar(&opts.MetricsAddr, "metrics-address", "", "address to serve Prometheus metrics on in format [host]:port (disabled if empty)")
 	flag.StringVar(&opts.ConfigPath, "config", "config.yaml", "path to YAML config file")
-	flag.TextVar(&opts.Provider, "provider", provider.BF2Hub, "provider to use as fallback if one cannot be selected based on player/server (bf2hub|playbf2|openspy|b2bf2)")
+	flag.DurationVar(&opts.ConfigPoll, "config-poll", 10*time.Second, "interval for checking the config file for changes to reload (0 to only reload on SIGHUP)")
+	flag.StringVar(&opts.Provider, "provider", "bf2hub", "provider to use as fallback if one cannot be selected based on player/server (bf2hub|playbf2|openspy|b2bf2|gameppy or any provider defined in the config)")
+	flag.BoolVar(&opts.ValidateResponses, "validate-responses", false, "replace malformed upstream responses with an ASP error response")
+	flag.BoolVar(&opts.SplitSnapshots, "split-snapshots", false, "only send each provider the snapshot data of its own players")
+	flag.DurationVar(&opts.SnapshotBackoff, "snapshot-backoff", time.Minute, "initial delay before retrying a failed snapshot delivery, doubled with every attempt (0 to disable retries)")
+	flag.DurationVar(&opts.SnapshotMaxAge, "snapshot-max-age", 24*time.Hour, "maximum age of snapshots to retry delivering")
+	flag.DurationVar(&opts.SnapshotRetention, "snapshot-retention", 7*24*time.Hour, "time after which to delete archived snapshots delivered to every provider (0 to keep all snapshots)")
+	flag.BoolVar(&opts.DebugHeaders, "debug-headers", false, "send the routing decision along with responses as X-Playerpath-* headers")
 	flag.Parse()
 	return opts
 }
+
+type ReplayOptions struct {
+	Provider provider.Provider
+	Limit    int
+}
+
+func InitReplay(args []string) *ReplayOptions {
+	opts := new(ReplayOptions)
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fs.TextVar(&opts.Provider, "provider", provider.Unknown, "provider to re-send failed snapshots to (bf2hub|playbf2|openspy|b2bf2|gameppy or any provider defined in the config)")
+	fs.IntVar(&opts.Limit, "limit", 100, "maximum number of snapshots to re-send")
+	_ = fs.Parse(args)
+	return opts
+}
+
+type OverrideOptions struct {
+	Action   string
+	PID      int
+	Provider provider.Provider
+	Author   string
+	Reason   string
+}
+
+func InitOverride(args []string) *OverrideOptions {
+	opts := new(OverrideOptions)
+	fs := flag.NewFlagSet("override", flag.ExitOnError)
+	fs.IntVar(&opts.PID, "pid", 0, "PID of the player to set/unset the override for")
+	fs.TextVar(&opts.Provider, "provider", provider.Unknown, "provider to pin the player to (bf2hub|playbf2|openspy|b2bf2|gameppy or any provider defined in the config)")
+	fs.StringVar(&opts.Author, "author", os.Getenv("USER"), "who is setting the override")
+	fs.StringVar(&opts.Reason, "reason", "", "why the override is being set")
+	if len(args) > 0 {
+		opts.Action = args[0]
+		_ = fs.Parse(args[1:])
+	}
+	return opts
+}
@@ -0,0 +1,217 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	delimiter = "\\"
+
+	keyEOF = "EOF"
+)
+
+var (
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+)
+
+type Pair struct {
+	Key   string
+	Value string
+}
+
+// Snapshot Post-round statistics snapshot as sent by a Battlefield 2 server (`prefix\mapname\key\value\...\EOF\1`).
+// The typed fields of Round and Player are decoded when parsing. Serialize always uses the raw key/value pairs,
+// which guarantees that an unmodified snapshot is serialized byte-identically (including any unknown keys).
+type Snapshot struct {
+	Prefix  string
+	MapName string
+	Round   Round
+	Players []Player
+	Trailer []Pair
+	// Warnings Values which could not be decoded into typed fields (the raw key/value pairs are retained regardless)
+	Warnings []error `json:"-"`
+}
+
+// Parse Parse a snapshot, grouping player keys carrying an index suffix (e.g. `pID_0`) into one Player per index
+func Parse(data string) (*Snapshot, error) {
+	elems := strings.Split(data, delimiter)
+	// Prefix and map name followed by key/value pairs
+	if len(elems) < 2 || len(elems)%2 != 0 {
+		return nil, fmt.Errorf("%w: unexpected number of elements: %d", ErrInvalidSnapshot, len(elems))
+	}
+
+	s := &Snapshot{
+		Prefix:  elems[0],
+		MapName: elems[1],
+	}
+
+	indices := make(map[int]int)
+	for i := 2; i < len(elems); i += 2 {
+		pair := Pair{Key: elems[i], Value: elems[i+1]}
+
+		key, index, ok := cutPlayerIndex(pair.Key)
+		if !ok {
+			// Anything after the players or the end marker is part of the trailer
+			if len(s.Players) == 0 && len(s.Trailer) == 0 && pair.Key != keyEOF {
+				if err := s.Round.decode(pair); err != nil {
+					s.Warnings = append(s.Warnings, err)
+				}
+			} else {
+				s.Trailer = append(s.Trailer, pair)
+			}
+			continue
+		}
+
+		if len(s.Trailer) > 0 {
+			return nil, fmt.Errorf("%w: player key %s after trailer", ErrInvalidSnapshot, pair.Key)
+		}
+
+		j, seen := indices[index]
+		if !seen {
+			j = len(s.Players)
+			indices[index] = j
+			s.Players = append(s.Players, Player{Index: index})
+		} else if j != len(s.Players)-1 {
+			// Serializing player blocks one after another would not reproduce the original order
+			return nil, fmt.Errorf("%w: non-contiguous keys for player %d", ErrInvalidSnapshot, index)
+		}
+
+		if err := s.Players[j].decode(Pair{Key: key, Value: pair.Value}); err != nil {
+			// Only the PID is required to route the player, any other value is merely passed on
+			if key == keyPID {
+				return nil, err
+			}
+			s.Warnings = append(s.Warnings, fmt.Errorf("player %d: %w", index, err))
+		}
+	}
+
+	return s, nil
+}
+
+// Validate Check the snapshot for completeness and consistency beyond what is required to parse it
+func (s *Snapshot) Validate() error {
+	if n := len(s.Trailer); n == 0 || s.Trailer[n-1] != (Pair{Key: keyEOF, Value: "1"}) {
+		return fmt.Errorf("%w: missing %s marker", ErrInvalidSnapshot, keyEOF)
+	}
+
+	for _, pair := range s.Trailer {
+		if pair.Key != keyEOF {
+			return fmt.Errorf("%w: unexpected key %s after players", ErrInvalidSnapshot, pair.Key)
+		}
+	}
+
+	if s.MapName == "" {
+		return fmt.Errorf("%w: missing map name", ErrInvalidSnapshot)
+	}
+
+	pids := make(map[int]struct{}, len(s.Players))
+	for _, p := range s.Players {
+		if _, ok := p.Get(keyPID); !ok {
+			return fmt.Errorf("%w: player %d is missing %s", ErrInvalidSnapshot, p.Index, keyPID)
+		}
+
+		if _, ok := pids[p.PID]; ok {
+			return fmt.Errorf("%w: duplicate %s %d", ErrInvalidSnapshot, keyPID, p.PID)
+		}
+		pids[p.PID] = struct{}{}
+	}
+
+	return nil
+}
+
+// Filter Create a copy of the snapshot containing only players for which keep returns true.
+// Remaining players are re-indexed (starting from 0) and the player count is updated accordingly.
+func (s *Snapshot) Filter(keep func(p Player) bool) *Snapshot {
+	filtered := &Snapshot{
+		Prefix:  s.Prefix,
+		MapName: s.MapName,
+		Round:   s.Round.clone(),
+		Players: make([]Player, 0, len(s.Players)),
+		Trailer: slices.Clone(s.Trailer),
+	}
+
+	for _, p := range s.Players {
+		if keep(p) {
+			c := p.clone()
+			c.Index = len(filtered.Players)
+			filtered.Players = append(filtered.Players, c)
+		}
+	}
+
+	filtered.Round.setPlayerCount(len(filtered.Players))
+
+	return filtered
+}
+
+func (s *Snapshot) Serialize() string {
+	elems := make([]string, 0, 2+2*len(s.Round.Fields)+2*len(s.Trailer))
+	elems = append(elems, s.Prefix, s.MapName)
+	for _, pair := range s.Round.Fields {
+		elems = append(elems, pair.Key, pair.Value)
+	}
+	for _, p := range s.Players {
+		suffix := "_" + strconv.Itoa(p.Index)
+		for _, field := range p.Fields {
+			elems = append(elems, field.Key+suffix, field.Value)
+		}
+	}
+	for _, pair := range s.Trailer {
+		elems = append(elems, pair.Key, pair.Value)
+	}
+
+	return strings.Join(elems, delimiter)
+}
+
+// cutPlayerIndex Split a player key such as `pID_0` into key and index
+func cutPlayerIndex(key string) (string, int, bool) {
+	i := strings.LastIndex(key, "_")
+	if i <= 0 {
+		return "", 0, false
+	}
+
+	index, ok := parseDigits(key[i+1:])
+	if !ok {
+		return "", 0, false
+	}
+
+	return key[:i], index, true
+}
+
+// cutItemID Split an item key such as `ktm0` into key and item id
+func cutItemID(key string) (string, int, bool) {
+	i := strings.IndexFunc(key, isDigit)
+	if i <= 0 {
+		return "", 0, false
+	}
+
+	id, ok := parseDigits(key[i:])
+	if !ok {
+		return "", 0, false
+	}
+
+	return key[:i], id, true
+}
+
+func parseDigits(s string) (int, bool) {
+	// May only contain decimal digits ([0-9])
+	if s == "" || strings.ContainsFunc(s, func(r rune) bool {
+		return !isDigit(r)
+	}) {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
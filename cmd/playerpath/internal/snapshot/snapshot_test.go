@@ -0,0 +1,237 @@
+package snapshot_test
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/snapshot"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestParse_Golden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.snapshot"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			// GIVEN
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			// WHEN
+			s, err := snapshot.Parse(string(data))
+
+			// THEN
+			require.NoError(t, err)
+			require.NoError(t, s.Validate())
+			assert.Equal(t, string(data), s.Serialize())
+
+			actual, err := json.MarshalIndent(s, "", "  ")
+			require.NoError(t, err)
+
+			golden := strings.TrimSuffix(path, ".snapshot") + ".golden.json"
+			if *update {
+				require.NoError(t, os.WriteFile(golden, actual, 0o644))
+			}
+
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(actual))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            string
+		wantErrContains string
+	}{
+		{
+			name: "parses snapshot with unknown keys",
+			data: `prefix\strike_at_karkand\pc\1\foo\bar\pID_0\1234567890\name_0\walterwhite\1031406_0\1\EOF\1`,
+		},
+		{
+			name:            "fails for odd number of elements",
+			data:            `prefix\strike_at_karkand\pc`,
+			wantErrContains: "unexpected number of elements",
+		},
+		{
+			name:            "fails for non-contiguous player keys",
+			data:            `prefix\strike_at_karkand\pID_0\1\pID_1\2\name_0\a\EOF\1`,
+			wantErrContains: "non-contiguous keys for player 0",
+		},
+		{
+			name:            "fails for player keys after trailer",
+			data:            `prefix\strike_at_karkand\pID_0\1\EOF\1\pID_1\2`,
+			wantErrContains: "player key pID_1 after trailer",
+		},
+		{
+			name:            "fails for non-numeric pid",
+			data:            `prefix\strike_at_karkand\pID_0\abc\EOF\1`,
+			wantErrContains: "invalid value for pID: abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			s, err := snapshot.Parse(tt.data)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				assert.ErrorIs(t, err, snapshot.ErrInvalidSnapshot)
+				assert.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.data, s.Serialize())
+			}
+		})
+	}
+}
+
+func TestParse_Warnings(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantWarnings []string
+	}{
+		{
+			name: "records invalid round value",
+			data: `prefix\strike_at_karkand\mapstart\\pc\1\pID_0\1\EOF\1`,
+			wantWarnings: []string{
+				"invalid snapshot: invalid value for mapstart: ",
+			},
+		},
+		{
+			name: "records invalid player values",
+			data: `prefix\strike_at_karkand\pID_0\1\ai_0\2\rs_0\1.5\ktm0_0\\EOF\1`,
+			wantWarnings: []string{
+				"player 0: invalid snapshot: invalid value for ai: 2",
+				"player 0: invalid snapshot: invalid value for rs: 1.5",
+				"player 0: invalid snapshot: invalid value for ktm0: ",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			s, err := snapshot.Parse(tt.data)
+
+			// THEN
+			require.NoError(t, err)
+			assert.NoError(t, s.Validate())
+			assert.Equal(t, tt.data, s.Serialize())
+			warnings := make([]string, 0, len(s.Warnings))
+			for _, w := range s.Warnings {
+				assert.ErrorIs(t, w, snapshot.ErrInvalidSnapshot)
+				warnings = append(warnings, w.Error())
+			}
+			assert.Equal(t, tt.wantWarnings, warnings)
+		})
+	}
+}
+
+func TestSnapshot_Validate(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            string
+		wantErrContains string
+	}{
+		{
+			name: "accepts complete snapshot",
+			data: `prefix\strike_at_karkand\pc\1\pID_0\1234567890\EOF\1`,
+		},
+		{
+			name:            "fails for missing EOF marker",
+			data:            `prefix\strike_at_karkand\pc\1\pID_0\1234567890`,
+			wantErrContains: "missing EOF marker",
+		},
+		{
+			name:            "fails for missing map name",
+			data:            `prefix\\pc\1\pID_0\1234567890\EOF\1`,
+			wantErrContains: "missing map name",
+		},
+		{
+			name:            "fails for player without pid",
+			data:            `prefix\strike_at_karkand\pc\1\name_0\walterwhite\EOF\1`,
+			wantErrContains: "player 0 is missing pID",
+		},
+		{
+			name:            "fails for duplicate pid",
+			data:            `prefix\strike_at_karkand\pc\2\pID_0\1234567890\pID_1\1234567890\EOF\1`,
+			wantErrContains: "duplicate pID 1234567890",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			s, err := snapshot.Parse(tt.data)
+			require.NoError(t, err)
+
+			// WHEN
+			err = s.Validate()
+
+			// THEN
+			if tt.wantErrContains != "" {
+				assert.ErrorIs(t, err, snapshot.ErrInvalidSnapshot)
+				assert.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSnapshot_Filter(t *testing.T) {
+	tests := []struct {
+		name     string
+		keep     func(p snapshot.Player) bool
+		expected string
+	}{
+		{
+			name:     "keeps all players",
+			keep:     func(p snapshot.Player) bool { return true },
+			expected: `prefix\strike_at_karkand\gameport\16567\pc\3\pID_0\11\name_0\a\pID_1\22\name_1\b\pID_2\33\name_2\c\EOF\1`,
+		},
+		{
+			name:     "re-indexes remaining players and updates player count",
+			keep:     func(p snapshot.Player) bool { return p.PID != 11 },
+			expected: `prefix\strike_at_karkand\gameport\16567\pc\2\pID_0\22\name_0\b\pID_1\33\name_1\c\EOF\1`,
+		},
+		{
+			name:     "keeps round for no remaining players",
+			keep:     func(p snapshot.Player) bool { return false },
+			expected: `prefix\strike_at_karkand\gameport\16567\pc\0\EOF\1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			s, err := snapshot.Parse(`prefix\strike_at_karkand\gameport\16567\pc\3\pID_0\11\name_0\a\pID_1\22\name_1\b\pID_2\33\name_2\c\EOF\1`)
+			require.NoError(t, err)
+
+			// WHEN
+			filtered := s.Filter(tt.keep)
+
+			// THEN
+			assert.Equal(t, tt.expected, filtered.Serialize())
+			require.NoError(t, filtered.Validate())
+			// Original must remain untouched
+			assert.Len(t, s.Players, 3)
+			assert.Equal(t, 3, s.Round.PlayerCount)
+		})
+	}
+}
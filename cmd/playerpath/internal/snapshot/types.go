@@ -0,0 +1,303 @@
+package snapshot
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+const (
+	keyGamePort    = "gameport"
+	keyQueryPort   = "queryport"
+	keyMapID       = "mapid"
+	keyMapStart    = "mapstart"
+	keyMapEnd      = "mapend"
+	keyWinner      = "win"
+	keyGameMode    = "gm"
+	keyMod         = "v"
+	keyPlayerCount = "pc"
+
+	keyPID           = "pID"
+	keyName          = "name"
+	keyTeam          = "t"
+	keyArmy          = "a"
+	keyTime          = "ctime"
+	keyCompleted     = "c"
+	keyIP            = "ip"
+	keyAI            = "ai"
+	keyScore         = "rs"
+	keyCombatScore   = "cs"
+	keyTeamScore     = "ts"
+	keyKills         = "kills"
+	keyDeaths        = "deaths"
+	keyRank          = "rank"
+	keyKitTime       = "ktm"
+	keyKitKills      = "kkl"
+	keyKitDeaths     = "kdt"
+	keyVehicleTime   = "vtm"
+	keyVehicleKills  = "vkl"
+	keyVehicleDeaths = "vdt"
+	keyVehicleRoad   = "vkr"
+	keyWeaponTime    = "wtm"
+	keyWeaponKills   = "wkl"
+	keyWeaponDeaths  = "wdt"
+)
+
+type Round struct {
+	// Fields contains the round's raw key/value pairs
+	Fields []Pair `json:"-"`
+
+	GamePort    int
+	QueryPort   int
+	MapID       int
+	MapStart    float64
+	MapEnd      float64
+	Winner      int
+	GameMode    int
+	Mod         string
+	PlayerCount int
+}
+
+func (r *Round) Get(key string) (string, bool) {
+	return get(r.Fields, key)
+}
+
+func (r *Round) decode(pair Pair) error {
+	r.Fields = append(r.Fields, pair)
+
+	var err error
+	switch pair.Key {
+	case keyGamePort:
+		r.GamePort, err = decodeInt(pair)
+	case keyQueryPort:
+		r.QueryPort, err = decodeInt(pair)
+	case keyMapID:
+		r.MapID, err = decodeInt(pair)
+	case keyMapStart:
+		r.MapStart, err = decodeFloat(pair)
+	case keyMapEnd:
+		r.MapEnd, err = decodeFloat(pair)
+	case keyWinner:
+		r.Winner, err = decodeInt(pair)
+	case keyGameMode:
+		r.GameMode, err = decodeInt(pair)
+	case keyMod:
+		r.Mod = pair.Value
+	case keyPlayerCount:
+		r.PlayerCount, err = decodeInt(pair)
+	}
+
+	return err
+}
+
+func (r *Round) setPlayerCount(count int) {
+	for i, pair := range r.Fields {
+		if pair.Key == keyPlayerCount {
+			r.Fields[i].Value = strconv.Itoa(count)
+			r.PlayerCount = count
+		}
+	}
+}
+
+func (r *Round) clone() Round {
+	c := *r
+	c.Fields = slices.Clone(r.Fields)
+	return c
+}
+
+type Player struct {
+	Index int
+	// Fields contains the player's raw key/value pairs, with keys stripped of the player index suffix
+	Fields []Pair `json:"-"`
+
+	PID         int
+	Name        string
+	Team        int
+	Army        int
+	Time        int
+	Completed   bool
+	IP          string
+	AI          bool
+	Score       int
+	CombatScore int
+	TeamScore   int
+	Kills       int
+	Deaths      int
+	Rank        int
+
+	Kits     []Kit
+	Vehicles []Vehicle
+	Weapons  []Weapon
+}
+
+type Kit struct {
+	ID     int
+	Time   int
+	Kills  int
+	Deaths int
+}
+
+type Vehicle struct {
+	ID        int
+	Time      int
+	Kills     int
+	Deaths    int
+	RoadKills int
+}
+
+type Weapon struct {
+	ID     int
+	Time   int
+	Kills  int
+	Deaths int
+}
+
+func (p *Player) Get(key string) (string, bool) {
+	return get(p.Fields, key)
+}
+
+func (p *Player) decode(pair Pair) error {
+	p.Fields = append(p.Fields, pair)
+
+	var err error
+	switch pair.Key {
+	case keyPID:
+		p.PID, err = decodeInt(pair)
+	case keyName:
+		p.Name = pair.Value
+	case keyTeam:
+		p.Team, err = decodeInt(pair)
+	case keyArmy:
+		p.Army, err = decodeInt(pair)
+	case keyTime:
+		p.Time, err = decodeInt(pair)
+	case keyCompleted:
+		p.Completed, err = decodeBool(pair)
+	case keyIP:
+		p.IP = pair.Value
+	case keyAI:
+		p.AI, err = decodeBool(pair)
+	case keyScore:
+		p.Score, err = decodeInt(pair)
+	case keyCombatScore:
+		p.CombatScore, err = decodeInt(pair)
+	case keyTeamScore:
+		p.TeamScore, err = decodeInt(pair)
+	case keyKills:
+		p.Kills, err = decodeInt(pair)
+	case keyDeaths:
+		p.Deaths, err = decodeInt(pair)
+	case keyRank:
+		p.Rank, err = decodeInt(pair)
+	default:
+		err = p.decodeItem(pair)
+	}
+
+	return err
+}
+
+func (p *Player) decodeItem(pair Pair) error {
+	key, id, ok := cutItemID(pair.Key)
+	if !ok {
+		// Unknown keys (e.g. awards) are only retained as raw fields
+		return nil
+	}
+
+	var err error
+	switch key {
+	case keyKitTime:
+		p.kit(id).Time, err = decodeInt(pair)
+	case keyKitKills:
+		p.kit(id).Kills, err = decodeInt(pair)
+	case keyKitDeaths:
+		p.kit(id).Deaths, err = decodeInt(pair)
+	case keyVehicleTime:
+		p.vehicle(id).Time, err = decodeInt(pair)
+	case keyVehicleKills:
+		p.vehicle(id).Kills, err = decodeInt(pair)
+	case keyVehicleDeaths:
+		p.vehicle(id).Deaths, err = decodeInt(pair)
+	case keyVehicleRoad:
+		p.vehicle(id).RoadKills, err = decodeInt(pair)
+	case keyWeaponTime:
+		p.weapon(id).Time, err = decodeInt(pair)
+	case keyWeaponKills:
+		p.weapon(id).Kills, err = decodeInt(pair)
+	case keyWeaponDeaths:
+		p.weapon(id).Deaths, err = decodeInt(pair)
+	}
+
+	return err
+}
+
+func (p *Player) kit(id int) *Kit {
+	i := slices.IndexFunc(p.Kits, func(k Kit) bool { return k.ID == id })
+	if i == -1 {
+		p.Kits = append(p.Kits, Kit{ID: id})
+		i = len(p.Kits) - 1
+	}
+	return &p.Kits[i]
+}
+
+func (p *Player) vehicle(id int) *Vehicle {
+	i := slices.IndexFunc(p.Vehicles, func(v Vehicle) bool { return v.ID == id })
+	if i == -1 {
+		p.Vehicles = append(p.Vehicles, Vehicle{ID: id})
+		i = len(p.Vehicles) - 1
+	}
+	return &p.Vehicles[i]
+}
+
+func (p *Player) weapon(id int) *Weapon {
+	i := slices.IndexFunc(p.Weapons, func(w Weapon) bool { return w.ID == id })
+	if i == -1 {
+		p.Weapons = append(p.Weapons, Weapon{ID: id})
+		i = len(p.Weapons) - 1
+	}
+	return &p.Weapons[i]
+}
+
+func (p *Player) clone() Player {
+	c := *p
+	c.Fields = slices.Clone(p.Fields)
+	c.Kits = slices.Clone(p.Kits)
+	c.Vehicles = slices.Clone(p.Vehicles)
+	c.Weapons = slices.Clone(p.Weapons)
+	return c
+}
+
+func get(pairs []Pair, key string) (string, bool) {
+	for _, pair := range pairs {
+		if pair.Key == key {
+			return pair.Value, true
+		}
+	}
+	return "", false
+}
+
+func decodeInt(pair Pair) (int, error) {
+	n, err := strconv.Atoi(pair.Value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid value for %s: %s", ErrInvalidSnapshot, pair.Key, pair.Value)
+	}
+	return n, nil
+}
+
+func decodeFloat(pair Pair) (float64, error) {
+	f, err := strconv.ParseFloat(pair.Value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid value for %s: %s", ErrInvalidSnapshot, pair.Key, pair.Value)
+	}
+	return f, nil
+}
+
+func decodeBool(pair Pair) (bool, error) {
+	switch pair.Value {
+	case "0":
+		return false, nil
+	case "1":
+		return true, nil
+	default:
+		return false, fmt.Errorf("%w: invalid value for %s: %s", ErrInvalidSnapshot, pair.Key, pair.Value)
+	}
+}
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -14,8 +21,15 @@ import (
 	"github.com/cetteup/playerpath/cmd/playerpath/internal/handler"
 	"github.com/cetteup/playerpath/cmd/playerpath/internal/modify"
 	"github.com/cetteup/playerpath/cmd/playerpath/internal/options"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/tracing"
+	archivesql "github.com/cetteup/playerpath/internal/domain/archive/sql"
+	"github.com/cetteup/playerpath/internal/domain/player"
+	"github.com/cetteup/playerpath/internal/domain/player/cached"
 	"github.com/cetteup/playerpath/internal/domain/player/sql"
 	"github.com/cetteup/playerpath/internal/domain/provider"
+	providersql "github.com/cetteup/playerpath/internal/domain/provider/sql"
+	responsesql "github.com/cetteup/playerpath/internal/domain/response/sql"
+	verificationsql "github.com/cetteup/playerpath/internal/domain/verification/sql"
 	"github.com/cetteup/playerpath/internal/sqlutil"
 )
 
@@ -54,6 +68,15 @@ func main() {
 			Msg("Failed to read config file")
 	}
 
+	// Resolve the default provider only now, since it may be one of the providers defined in the config
+	defaultProvider, err := resolveDefaultProvider(cfg, opts)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str("provider", opts.Provider).
+			Msg("Invalid default provider")
+	}
+
 	db := sqlutil.Connect(
 		cfg.Database.Hostname,
 		cfg.Database.DatabaseName,
@@ -69,23 +92,141 @@ func main() {
 		}
 	}()
 
-	servers := make(map[string]provider.Provider, len(cfg.Servers))
-	for _, server := range cfg.Servers {
-		servers[server.IP] = server.Provider
+	providers := providersql.NewRepository(db)
+	if err = providers.Sync(context.Background(), cfg.Catalogue); err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed to sync providers to database")
+	}
+	if err = provider.Load(cfg.Catalogue); err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed to load providers")
 	}
 
-	repository := sql.NewRepository(db)
-	h := handler.NewHandler(repository, servers, opts.Provider)
-	h.WithModifier(
-		modify.HostRequestModifier{},
-		modify.InfoQueryRequestModifier{},
-		modify.VerificationResponseModifier{},
-	)
+	servers := buildServers(cfg.Servers)
+	var repository player.Repository = sql.NewRepository(db)
+	var players *cached.Repository
+	if cfg.Cache.Players.TTL > 0 {
+		players = cached.NewRepository(
+			repository,
+			cmp.Or(cfg.Cache.Players.Size, 10000),
+			cfg.Cache.Players.TTL,
+			cfg.Cache.Players.Negative,
+		)
+		repository = players
+	}
+	snapshots := archivesql.NewRepository(db)
+	h := handler.NewHandler(repository, servers, defaultProvider)
+	h.WithModifier(buildModifiers(cfg, opts)...)
+	if len(cfg.Upstreams) > 0 {
+		if err = h.WithUpstreams(buildUpstreams(cfg.Upstreams)); err != nil {
+			log.Fatal().
+				Err(err).
+				Msg("Failed to configure upstreams")
+		}
+	}
+	h.WithCircuitBreaker(cfg.Breaker.Threshold, cfg.Breaker.Cooldown)
+	if len(cfg.Cache.Endpoints) > 0 {
+		ttls := make(map[string]handler.CacheTTL, len(cfg.Cache.Endpoints))
+		for endpoint, c := range cfg.Cache.Endpoints {
+			ttls[endpoint] = handler.CacheTTL{TTL: c.TTL, Stale: c.Stale}
+		}
+		h.WithResponseCache(ttls, cmp.Or(cfg.Cache.Size, 10000))
+	}
+	h.WithFailover(cfg.Failover.Endpoints, cfg.Failover.Timeout)
+	if len(cfg.Fallback.Endpoints) > 0 {
+		h.WithFallback(responsesql.NewRepository(db), cfg.Fallback.Endpoints, cfg.Fallback.Refresh)
+	}
+	if len(cfg.Discovery.Providers) > 0 {
+		h.WithPlayerDiscovery(
+			cfg.Discovery.Providers,
+			cmp.Or(cfg.Discovery.Timeout, 2*time.Second),
+			cmp.Or(cfg.Discovery.TTL, 10*time.Minute),
+		)
+	}
+	if cfg.Verification.History {
+		h.WithVerificationHistory(verificationsql.NewRepository(db))
+	}
+	h.WithSnapshotArchive(snapshots)
+	h.WithSnapshotQueue(opts.SnapshotBackoff, opts.SnapshotMaxAge)
+	if opts.SplitSnapshots {
+		h.WithSnapshotSplitting()
+	}
+	if opts.DebugHeaders {
+		h.WithDebugHeaders()
+	}
+
+	// Re-send failed snapshots instead of serving requests
+	if flag.Arg(0) == "replay" {
+		replay(h, snapshots, options.InitReplay(flag.Args()[1:]))
+		return
+	}
+
+	// Manage player provider overrides instead of serving requests
+	if flag.Arg(0) == "override" {
+		override(repository, options.InitOverride(flag.Args()[1:]))
+		return
+	}
+
+	r := newReloader(h, providers, opts)
+	go r.watch(context.Background(), opts.ConfigPoll)
+
+	if opts.MetricsAddr != "" {
+		go serveMetrics(opts.MetricsAddr)
+	}
+
+	if cfg.Admin.Address != "" {
+		go serveAdmin(h, cfg.Admin, r)
+	}
+
+	if opts.SnapshotBackoff > 0 {
+		go h.RunSnapshotQueue(context.Background(), 10*time.Second)
+	}
+
+	if opts.SnapshotRetention > 0 {
+		go h.RunSnapshotPruning(context.Background(), time.Hour, opts.SnapshotRetention)
+	}
+
+	if players != nil {
+		go invalidatePlayers(context.Background(), players, cmp.Or(cfg.Cache.Players.Poll, 30*time.Second))
+	}
 
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
 	e.Use(middleware.Recover())
+	if cfg.Tracing.Endpoint != "" {
+		ratio := 1.0
+		if cfg.Tracing.Ratio != nil {
+			ratio = *cfg.Tracing.Ratio
+		}
+		shutdown, err2 := tracing.Init(
+			context.Background(),
+			cfg.Tracing.Endpoint,
+			cfg.Tracing.Insecure,
+			ratio,
+			buildVersion,
+		)
+		if err2 != nil {
+			log.Fatal().
+				Err(err2).
+				Str("endpoint", cfg.Tracing.Endpoint).
+				Msg("Failed to initialize tracing")
+		}
+		// Flush any buffered spans once the server has been shut down
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err3 := shutdown(ctx)
+			if err3 != nil {
+				log.Error().
+					Err(err3).
+					Msg("Failed to shut down tracing")
+			}
+		}()
+		e.Use(tracing.Middleware())
+	}
 	e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
 		Timeout: time.Second * 10,
 	}))
@@ -107,6 +248,7 @@ func main() {
 				Str("latency", v.Latency.Truncate(time.Millisecond).String()).
 				Str("agent", v.UserAgent).
 				Any("provider", c.Get("provider")).
+				Any("decision", c.Get("decision")).
 				Msg("request")
 
 			return nil
@@ -120,8 +262,118 @@ func main() {
 	asp.GET("/getunlocksinfo.aspx", h.HandleDynamicForward)
 	asp.GET("/getrankinfo.aspx", h.HandleDynamicForward)
 	asp.GET("/VerifyPlayer.aspx", h.HandleDynamicForward)
+	// Snapshots forwarded to providers of all players in the round
+	asp.POST("/sendsnapshot.aspx", h.HandleSnapshotForward)
 	// Fallback forward to default provider
 	asp.Any("/*.aspx", h.HandleStaticForward)
 
-	e.Logger.Fatal(e.Start(opts.ListenAddr))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		if err2 := e.Start(opts.ListenAddr); err2 != nil && !errors.Is(err2, http.ErrServerClosed) {
+			log.Fatal().
+				Err(err2).
+				Str("address", opts.ListenAddr).
+				Msg("Failed to start server")
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info().Msg("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err = e.Shutdown(shutdownCtx); err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to shut down server gracefully")
+	}
+}
+
+func buildServers(configs []config.ServerConfig) map[string]provider.Provider {
+	servers := make(map[string]provider.Provider, len(configs))
+	for _, server := range configs {
+		servers[server.IP] = server.Provider
+	}
+	return servers
+}
+
+func buildUpstreams(configs map[provider.Provider]config.UpstreamConfig) map[provider.Provider]handler.Upstream {
+	upstreams := make(map[provider.Provider]handler.Upstream, len(configs))
+	for pv, c := range configs {
+		upstream := handler.Upstream{
+			URL:       c.URL,
+			Mirrors:   c.Mirrors,
+			Threshold: c.Threshold,
+			Cooldown:  c.Cooldown,
+		}
+		if t := c.Transport; t != nil {
+			upstream.Transport = &handler.Transport{
+				DialTimeout:           t.DialTimeout,
+				TLSHandshakeTimeout:   t.TLSHandshakeTimeout,
+				ResponseHeaderTimeout: t.ResponseHeaderTimeout,
+				MaxIdleConns:          t.MaxIdleConns,
+				Proxy:                 t.Proxy,
+				CAFile:                t.CAFile,
+			}
+		}
+		upstreams[pv] = upstream
+	}
+	return upstreams
+}
+
+// invalidatePlayers Periodically check for players imported by the importer, which bypasses the cache,
+// clearing the cache if there are any
+func invalidatePlayers(ctx context.Context, repository *cached.Repository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := repository.Invalidate(ctx); err != nil {
+			log.Error().
+				Err(err).
+				Msg("Failed to check for imported players")
+		}
+	}
+}
+
+// resolveDefaultProvider Determine the default provider, preferring the one set in the config over the -provider flag
+func resolveDefaultProvider(cfg config.Config, opts *options.Options) (provider.Provider, error) {
+	if cfg.Provider != provider.Unknown {
+		return cfg.Provider, nil
+	}
+
+	pv, err := provider.Parse(cfg.Catalogue, opts.Provider)
+	if err != nil {
+		return provider.Unknown, err
+	}
+	if pv == provider.Unknown {
+		return provider.Unknown, errors.New("default provider must be set")
+	}
+
+	return pv, nil
+}
+
+func buildModifiers(cfg config.Config, opts *options.Options) []modify.Modifier {
+	modifiers := []modify.Modifier{
+		modify.HostRequestModifier{},
+		modify.InfoQueryRequestModifier{},
+		modify.VerificationResponseModifier{},
+	}
+	validate := opts.ValidateResponses
+	if cfg.ValidateResponses != nil {
+		validate = *cfg.ValidateResponses
+	}
+	if validate {
+		// Validate last to ensure any previous modifications are valid as well
+		modifiers = append(modifiers, modify.ValidationResponseModifier{})
+	}
+	return modifiers
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/options"
+	"github.com/cetteup/playerpath/internal/domain/player"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/trace"
+)
+
+// override Manage manual player provider overrides (set|unset|list)
+func override(repository player.Repository, opts *options.OverrideOptions) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch opts.Action {
+	case "set":
+		if opts.PID == 0 || opts.Provider == provider.Unknown || opts.Author == "" || opts.Reason == "" {
+			log.Fatal().Msg("PID, provider, author and reason are required to set an override")
+		}
+
+		err := repository.UpsertOverride(ctx, player.Override{
+			PID:      opts.PID,
+			Provider: opts.Provider,
+			Author:   opts.Author,
+			Reason:   opts.Reason,
+			Updated:  time.Now().UTC(),
+		})
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Int(trace.LogPlayerPID, opts.PID).
+				Msg("Failed to set override")
+		}
+
+		log.Info().
+			Int(trace.LogPlayerPID, opts.PID).
+			Stringer(trace.LogProvider, opts.Provider).
+			Msg("Set override")
+	case "unset":
+		if opts.PID == 0 {
+			log.Fatal().Msg("PID is required to unset an override")
+		}
+
+		if err := repository.DeleteOverride(ctx, opts.PID); err != nil {
+			log.Fatal().
+				Err(err).
+				Int(trace.LogPlayerPID, opts.PID).
+				Msg("Failed to unset override")
+		}
+
+		log.Info().
+			Int(trace.LogPlayerPID, opts.PID).
+			Msg("Unset override")
+	case "list":
+		overrides, err := repository.FindOverrides(ctx)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Msg("Failed to list overrides")
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "PID\tPROVIDER\tAUTHOR\tUPDATED\tREASON")
+		for _, o := range overrides {
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", o.PID, o.Provider, o.Author, o.Updated.Format(time.RFC3339), o.Reason)
+		}
+		_ = w.Flush()
+	default:
+		log.Fatal().Msgf("Unknown override action %q, expected set, unset or list", opts.Action)
+	}
+}
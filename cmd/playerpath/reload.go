@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/config"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/handler"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/options"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+// reloader Re-reads the config file, applying the parts which can be changed while serving requests
+// (servers, default provider, response validation and provider definitions). Any other changes require a restart.
+type reloader struct {
+	h         *handler.Handler
+	providers provider.Repository
+	opts      *options.Options
+
+	mu       sync.Mutex
+	checksum []byte
+}
+
+func newReloader(h *handler.Handler, providers provider.Repository, opts *options.Options) *reloader {
+	r := &reloader{
+		h:         h,
+		providers: providers,
+		opts:      opts,
+	}
+	// Remember the config file's current state, so polling only triggers a reload once it changes
+	r.checksum, _ = checksumFile(opts.ConfigPath)
+	return r
+}
+
+// reload Load and validate the config, only replacing the handler's settings if the config is valid
+func (r *reloader) reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	checksum, err := checksumFile(r.opts.ConfigPath)
+	if err != nil {
+		return err
+	}
+	// Remember the content even if it turns out to be invalid, else an invalid config would be retried on every poll
+	r.checksum = checksum
+
+	cfg, err := config.LoadConfig(r.opts.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	pv, err := resolveDefaultProvider(cfg, r.opts)
+	if err != nil {
+		return err
+	}
+
+	// Ensure any newly defined providers can be referenced in the database
+	if err = r.providers.Sync(ctx, cfg.Catalogue); err != nil {
+		return err
+	}
+
+	// Only replace the catalogue once the config is known to be valid, so it always matches the handler's settings
+	if err = provider.Load(cfg.Catalogue); err != nil {
+		return err
+	}
+	r.h.Reload(buildServers(cfg.Servers), pv, buildModifiers(cfg, r.opts)...)
+
+	log.Info().
+		Int("servers", len(cfg.Servers)).
+		Stringer("default", pv).
+		Msg("Reloaded config")
+
+	return nil
+}
+
+// watch Reload the config on SIGHUP and whenever the config file changes (checked at the given interval, 0 to disable)
+func (r *reloader) watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var poll <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-poll:
+			if !r.changed() {
+				continue
+			}
+		}
+
+		if err := r.reload(ctx); err != nil {
+			log.Error().
+				Err(err).
+				Str("config", r.opts.ConfigPath).
+				Msg("Failed to reload config, keeping previous config")
+		}
+	}
+}
+
+// changed Determine whether the config file's content differs from the one last (attempted to be) loaded
+func (r *reloader) changed() bool {
+	checksum, err := checksumFile(r.opts.ConfigPath)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("config", r.opts.ConfigPath).
+			Msg("Failed to check config file for changes")
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !bytes.Equal(checksum, r.checksum)
+}
+
+func checksumFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := sha256.Sum256(content)
+	return checksum[:], nil
+}
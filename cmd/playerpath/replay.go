@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/handler"
+	"github.com/cetteup/playerpath/cmd/playerpath/internal/options"
+	"github.com/cetteup/playerpath/internal/domain/archive"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/trace"
+)
+
+// replay Re-send archived snapshots which failed to be delivered to the given provider
+func replay(h *handler.Handler, repository archive.Repository, opts *options.ReplayOptions) {
+	if opts.Provider == provider.Unknown {
+		log.Fatal().Msg("Provider is required to replay snapshots")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	deliveries, err := repository.FindDeliveriesByStatus(ctx, opts.Provider, archive.StatusFailed, opts.Limit)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Stringer(trace.LogProvider, opts.Provider).
+			Msg("Failed to find failed snapshot deliveries")
+	}
+
+	log.Info().
+		Stringer(trace.LogProvider, opts.Provider).
+		Msgf("Replaying %d snapshots", len(deliveries))
+
+	replayed := 0
+	for _, d := range deliveries {
+		s, err2 := repository.FindByID(ctx, d.SnapshotID)
+		if err2 != nil {
+			log.Error().
+				Err(err2).
+				Int64("snapshot", d.SnapshotID).
+				Msg("Failed to load snapshot")
+			continue
+		}
+
+		if err2 = h.ReplaySnapshot(ctx, s, d); err2 != nil {
+			log.Error().
+				Err(err2).
+				Stringer(trace.LogProvider, opts.Provider).
+				Int64("snapshot", d.SnapshotID).
+				Msg("Failed to replay snapshot")
+			continue
+		}
+
+		replayed++
+	}
+
+	log.Info().
+		Stringer(trace.LogProvider, opts.Provider).
+		Int("failed", len(deliveries)-replayed).
+		Msgf("Replayed %d snapshots", replayed)
+}
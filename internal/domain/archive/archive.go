@@ -0,0 +1,38 @@
+//go:generate go tool stringer -type=Status -trimprefix=Status
+package archive
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+type Status int
+
+const (
+	StatusUnknown   Status = 0
+	StatusPending   Status = 1
+	StatusDelivered Status = 2
+	StatusFailed    Status = 3
+)
+
+type Snapshot struct {
+	ID       int64
+	ServerIP string
+	Header   http.Header
+	Data     []byte
+	Received time.Time
+}
+
+type Delivery struct {
+	SnapshotID int64
+	Provider   provider.Provider
+	Status     Status
+	StatusCode int
+	Error      string
+	Attempts   int
+	// NextAttempt is the earliest time a pending delivery should be attempted (again)
+	NextAttempt time.Time
+	Updated     time.Time
+}
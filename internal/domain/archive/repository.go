@@ -0,0 +1,24 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+)
+
+type Repository interface {
+	Insert(ctx context.Context, snapshot Snapshot) (int64, error)
+	FindByID(ctx context.Context, id int64) (Snapshot, error)
+	UpsertDelivery(ctx context.Context, delivery Delivery) error
+	FindDeliveriesByStatus(ctx context.Context, pv provider.Provider, status Status, limit int) ([]Delivery, error)
+	FindDueDeliveries(ctx context.Context, before time.Time, limit int) ([]Delivery, error)
+	// DeleteDelivered Delete up to limit snapshots received before the given time which were delivered to every
+	// provider (along with their deliveries), returning the number of deleted snapshots
+	DeleteDelivered(ctx context.Context, before time.Time, limit int) (int, error)
+}
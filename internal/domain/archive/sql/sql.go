@@ -0,0 +1,247 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/cetteup/playerpath/internal/domain/archive"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/sqlutil"
+)
+
+const (
+	snapshotTable = "snapshots"
+	deliveryTable = "snapshot_deliveries"
+
+	columnID       = "id"
+	columnServer   = "server"
+	columnHeader   = "header"
+	columnData     = "data"
+	columnReceived = "received"
+
+	columnSnapshot    = "snapshot"
+	columnProvider    = "provider"
+	columnStatus      = "status"
+	columnStatusCode  = "code"
+	columnError       = "error"
+	columnAttempts    = "attempts"
+	columnNextAttempt = "next_attempt"
+	columnUpdated     = "updated"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Insert(ctx context.Context, snapshot archive.Snapshot) (int64, error) {
+	header, err := json.Marshal(snapshot.Header)
+	if err != nil {
+		return 0, err
+	}
+
+	query := sq.
+		Insert(snapshotTable).
+		Columns(
+			columnServer,
+			columnHeader,
+			columnData,
+			columnReceived,
+		).
+		Values(
+			snapshot.ServerIP,
+			header,
+			snapshot.Data,
+			snapshot.Received,
+		)
+
+	result, err := query.RunWith(r.db).ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (r *Repository) FindByID(ctx context.Context, id int64) (archive.Snapshot, error) {
+	query := sq.
+		Select(
+			columnID,
+			columnServer,
+			columnHeader,
+			columnData,
+			columnReceived,
+		).
+		From(snapshotTable).
+		Where(sq.Eq{columnID: id})
+
+	var s archive.Snapshot
+	var header []byte
+	err := query.RunWith(r.db).QueryRowContext(ctx).Scan(
+		&s.ID,
+		&s.ServerIP,
+		&header,
+		&s.Data,
+		&s.Received,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return archive.Snapshot{}, archive.ErrSnapshotNotFound
+		}
+		return archive.Snapshot{}, err
+	}
+
+	if err = json.Unmarshal(header, &s.Header); err != nil {
+		return archive.Snapshot{}, err
+	}
+
+	return s, nil
+}
+
+func (r *Repository) UpsertDelivery(ctx context.Context, delivery archive.Delivery) error {
+	query := sq.
+		Insert(deliveryTable).
+		Columns(
+			columnSnapshot,
+			columnProvider,
+			columnStatus,
+			columnStatusCode,
+			columnError,
+			columnAttempts,
+			columnNextAttempt,
+			columnUpdated,
+		).
+		Values(
+			delivery.SnapshotID,
+			delivery.Provider,
+			delivery.Status,
+			delivery.StatusCode,
+			// Error column is limited to 255 characters
+			sqlutil.Truncate(delivery.Error, 255),
+			delivery.Attempts,
+			delivery.NextAttempt,
+			delivery.Updated,
+		).
+		Suffix(fmt.Sprintf("ON DUPLICATE KEY UPDATE %s", strings.Join([]string{
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnStatus),
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnStatusCode),
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnError),
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnAttempts),
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnNextAttempt),
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnUpdated),
+		}, ", ")))
+
+	_, err := query.RunWith(r.db).ExecContext(ctx)
+	return err
+}
+
+func (r *Repository) FindDeliveriesByStatus(
+	ctx context.Context,
+	pv provider.Provider,
+	status archive.Status,
+	limit int,
+) ([]archive.Delivery, error) {
+	return r.findDeliveries(ctx, sq.And{
+		sq.Eq{columnProvider: pv},
+		sq.Eq{columnStatus: status},
+	}, columnSnapshot, limit)
+}
+
+func (r *Repository) FindDueDeliveries(ctx context.Context, before time.Time, limit int) ([]archive.Delivery, error) {
+	return r.findDeliveries(ctx, sq.And{
+		sq.Eq{columnStatus: archive.StatusPending},
+		sq.LtOrEq{columnNextAttempt: before},
+	}, columnNextAttempt, limit)
+}
+
+func (r *Repository) DeleteDelivered(ctx context.Context, before time.Time, limit int) (int, error) {
+	// Deliveries are deleted along with the snapshot (ON DELETE CASCADE)
+	query := sq.
+		Delete(snapshotTable).
+		Where(sq.And{
+			sq.Lt{columnReceived: before},
+			sq.Expr(fmt.Sprintf(
+				"NOT EXISTS (SELECT 1 FROM %[1]s WHERE %[1]s.%[2]s = %[3]s.%[4]s AND %[1]s.%[5]s <> ?)",
+				deliveryTable, columnSnapshot, snapshotTable, columnID, columnStatus,
+			), archive.StatusDelivered),
+		}).
+		OrderBy(
+			fmt.Sprintf("%s ASC", columnID),
+		).
+		Limit(uint64(limit))
+
+	result, err := query.RunWith(r.db).ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(deleted), nil
+}
+
+func (r *Repository) findDeliveries(ctx context.Context, where sq.Sqlizer, orderBy string, limit int) ([]archive.Delivery, error) {
+	query := sq.
+		Select(
+			columnSnapshot,
+			columnProvider,
+			columnStatus,
+			columnStatusCode,
+			columnError,
+			columnAttempts,
+			columnNextAttempt,
+			columnUpdated,
+		).
+		From(deliveryTable).
+		Where(where).
+		OrderBy(
+			fmt.Sprintf("%s ASC", orderBy),
+		).
+		Limit(uint64(limit))
+
+	rows, err := query.RunWith(r.db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	deliveries := make([]archive.Delivery, 0)
+	for rows.Next() {
+		var d archive.Delivery
+		if err = rows.Scan(
+			&d.SnapshotID,
+			&d.Provider,
+			&d.Status,
+			&d.StatusCode,
+			&d.Error,
+			&d.Attempts,
+			&d.NextAttempt,
+			&d.Updated,
+		); err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
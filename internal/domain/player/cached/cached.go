@@ -0,0 +1,112 @@
+package cached
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cetteup/playerpath/internal/domain/player"
+	"github.com/cetteup/playerpath/internal/pkg/cache"
+)
+
+// Repository Caching decorator for a player.Repository, keeping lookup results (including misses) in memory.
+// Entries are invalidated on any write. Writes made by other processes (e.g. the importer) are picked up
+// whenever Invalidate is called or once the respective entry expires.
+type Repository struct {
+	repository  player.Repository
+	entries     *cache.Cache[[]player.Player]
+	overrides   *cache.Cache[[]player.Override]
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mu           sync.Mutex
+	lastImported time.Time
+}
+
+// NewRepository Wrap the repository, caching up to maxEntries players for the given ttl
+// and players which were not found for the given negativeTTL
+func NewRepository(repository player.Repository, maxEntries int, ttl time.Duration, negativeTTL time.Duration) *Repository {
+	return &Repository{
+		repository:  repository,
+		entries:     cache.New[[]player.Player](maxEntries),
+		overrides:   cache.New[[]player.Override](maxEntries),
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+func (r *Repository) UpsertMany(ctx context.Context, players []player.Player) (int, error) {
+	modified, err := r.repository.UpsertMany(ctx, players)
+
+	// Invalidate even if the upsert failed, since some rows may have been modified regardless
+	for _, p := range players {
+		r.entries.Delete(getKey(p.PID))
+	}
+
+	return modified, err
+}
+
+func (r *Repository) FindByPID(ctx context.Context, pid int) (player.Player, error) {
+	players, err := r.FindAllByPID(ctx, pid)
+	if err != nil {
+		return player.Player{}, err
+	}
+
+	if len(players) == 0 {
+		return player.Player{}, player.ErrPlayerNotFound
+	}
+	if len(players) > 1 {
+		return player.Player{}, player.ErrMultiplePlayersFound
+	}
+
+	return players[0], nil
+}
+
+func (r *Repository) FindAllByPID(ctx context.Context, pid int) ([]player.Player, error) {
+	key := getKey(pid)
+	if players, freshness := r.entries.Get(key); freshness == cache.Fresh {
+		return players, nil
+	}
+
+	players, err := r.repository.FindAllByPID(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(players) > 0 {
+		r.entries.Set(key, players, r.ttl, 0)
+	} else if r.negativeTTL > 0 {
+		r.entries.Set(key, players, r.negativeTTL, 0)
+	}
+
+	return players, nil
+}
+
+func (r *Repository) FindLastImported(ctx context.Context) (time.Time, error) {
+	return r.repository.FindLastImported(ctx)
+}
+
+// Invalidate Clear all cached players if any players have been imported since the last call
+// (by any process, e.g. the importer)
+func (r *Repository) Invalidate(ctx context.Context) error {
+	imported, err := r.repository.FindLastImported(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !imported.Equal(r.lastImported) {
+		// Any cached player (or absence thereof) may be outdated, since imports are not limited to new PIDs
+		r.entries.Clear()
+		r.lastImported = imported
+	}
+
+	return nil
+}
+
+func getKey(pid int) string {
+	return strconv.Itoa(pid)
+}
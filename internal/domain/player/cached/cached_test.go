@@ -0,0 +1,186 @@
+package cached_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cetteup/playerpath/internal/domain/player"
+	"github.com/cetteup/playerpath/internal/domain/player/cached"
+	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/pkg/cache"
+)
+
+type fakeRepository struct {
+	players map[int]player.Player
+	finds   int
+}
+
+func (r *fakeRepository) UpsertMany(_ context.Context, players []player.Player) (int, error) {
+	for _, p := range players {
+		r.players[p.PID] = p
+	}
+	return len(players), nil
+}
+
+func (r *fakeRepository) FindByPID(_ context.Context, _ int) (player.Player, error) {
+	return player.Player{}, errors.New("fakeRepository: decorator should only use FindAllByPID")
+}
+
+func (r *fakeRepository) FindAllByPID(_ context.Context, pid int) ([]player.Player, error) {
+	r.finds++
+	p, ok := r.players[pid]
+	if !ok {
+		return []player.Player{}, nil
+	}
+	return []player.Player{p}, nil
+}
+
+func (r *fakeRepository) FindLastImported(_ context.Context) (time.Time, error) {
+	var last time.Time
+	for _, p := range r.players {
+		if p.Imported.After(last) {
+			last = p.Imported
+		}
+	}
+	return last, nil
+}
+
+func (r *fakeRepository) UpsertOverride(_ context.Context, _ player.Override) error {
+	return nil
+}
+
+func (r *fakeRepository) DeleteOverride(_ context.Context, _ int) error {
+	return nil
+}
+
+func (r *fakeRepository) FindOverrideByPID(_ context.Context, _ int) (player.Override, error) {
+	return player.Override{}, player.ErrOverrideNotFound
+}
+
+func (r *fakeRepository) FindOverrides(_ context.Context) ([]player.Override, error) {
+	return []player.Override{}, nil
+}
+
+func TestRepository_FindByPID(t *testing.T) {
+	known := player.Player{PID: 45377286, Nick: "mister249", Provider: provider.BF2Hub}
+
+	tests := []struct {
+		name       string
+		pid        int
+		advance    time.Duration
+		wantPlayer player.Player
+		wantErr    error
+		wantFinds  int
+	}{
+		{
+			name:       "serves player from cache within ttl",
+			pid:        known.PID,
+			advance:    4 * time.Minute,
+			wantPlayer: known,
+			wantFinds:  1,
+		},
+		{
+			name:       "looks up player again after ttl",
+			pid:        known.PID,
+			advance:    5 * time.Minute,
+			wantPlayer: known,
+			wantFinds:  2,
+		},
+		{
+			name:      "serves not found from cache within negative ttl",
+			pid:       500000000,
+			advance:   59 * time.Second,
+			wantErr:   player.ErrPlayerNotFound,
+			wantFinds: 1,
+		},
+		{
+			name:      "looks up not found player again after negative ttl",
+			pid:       500000000,
+			advance:   time.Minute,
+			wantErr:   player.ErrPlayerNotFound,
+			wantFinds: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			now := time.Date(2026, 2, 17, 23, 0, 0, 0, time.UTC)
+			cache.Now = func() time.Time { return now }
+			t.Cleanup(func() { cache.Now = time.Now })
+
+			underlying := &fakeRepository{players: map[int]player.Player{known.PID: known}}
+			repository := cached.NewRepository(underlying, 10, 5*time.Minute, time.Minute)
+			_, _ = repository.FindByPID(context.Background(), tt.pid)
+
+			// WHEN
+			now = now.Add(tt.advance)
+			p, err := repository.FindByPID(context.Background(), tt.pid)
+
+			// THEN
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantPlayer, p)
+			assert.Equal(t, tt.wantFinds, underlying.finds)
+		})
+	}
+}
+
+func TestRepository_UpsertMany(t *testing.T) {
+	// GIVEN
+	underlying := &fakeRepository{players: map[int]player.Player{}}
+	repository := cached.NewRepository(underlying, 10, 5*time.Minute, time.Minute)
+	_, err := repository.FindByPID(context.Background(), 45377286)
+	require.ErrorIs(t, err, player.ErrPlayerNotFound)
+
+	// WHEN
+	upserted := player.Player{PID: 45377286, Nick: "mister249", Provider: provider.PlayBF2}
+	_, err = repository.UpsertMany(context.Background(), []player.Player{upserted})
+	require.NoError(t, err)
+
+	// THEN
+	p, err := repository.FindByPID(context.Background(), 45377286)
+	require.NoError(t, err)
+	assert.Equal(t, upserted, p)
+	assert.Equal(t, 2, underlying.finds)
+}
+
+func TestRepository_Invalidate(t *testing.T) {
+	// GIVEN
+	underlying := &fakeRepository{players: map[int]player.Player{}}
+	repository := cached.NewRepository(underlying, 10, 5*time.Minute, time.Minute)
+	require.NoError(t, repository.Invalidate(context.Background()))
+	_, err := repository.FindByPID(context.Background(), 45377286)
+	require.ErrorIs(t, err, player.ErrPlayerNotFound)
+
+	// WHEN
+	// Player is imported by another process, bypassing the decorator
+	imported := player.Player{
+		PID:      45377286,
+		Nick:     "mister249",
+		Provider: provider.PlayBF2,
+		Imported: time.Date(2026, 2, 17, 23, 0, 0, 0, time.UTC),
+	}
+	underlying.players[imported.PID] = imported
+	require.NoError(t, repository.Invalidate(context.Background()))
+
+	// THEN
+	p, err := repository.FindByPID(context.Background(), 45377286)
+	require.NoError(t, err)
+	assert.Equal(t, imported, p)
+	assert.Equal(t, 2, underlying.finds)
+
+	// Nothing was imported since, so cached players remain
+	require.NoError(t, repository.Invalidate(context.Background()))
+	_, err = repository.FindByPID(context.Background(), 45377286)
+	require.NoError(t, err)
+	assert.Equal(t, 2, underlying.finds)
+}
@@ -0,0 +1,48 @@
+package cached
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cetteup/playerpath/internal/domain/player"
+	"github.com/cetteup/playerpath/internal/pkg/cache"
+)
+
+func (r *Repository) UpsertOverride(ctx context.Context, override player.Override) error {
+	defer r.overrides.Delete(getKey(override.PID))
+	return r.repository.UpsertOverride(ctx, override)
+}
+
+func (r *Repository) DeleteOverride(ctx context.Context, pid int) error {
+	defer r.overrides.Delete(getKey(pid))
+	return r.repository.DeleteOverride(ctx, pid)
+}
+
+func (r *Repository) FindOverrideByPID(ctx context.Context, pid int) (player.Override, error) {
+	// Cache overrides as a slice of (at most) one, allowing to cache the absence of an override as well
+	key := getKey(pid)
+	overrides, freshness := r.overrides.Get(key)
+	if freshness != cache.Fresh {
+		o, err := r.repository.FindOverrideByPID(ctx, pid)
+		if err != nil && !errors.Is(err, player.ErrOverrideNotFound) {
+			return player.Override{}, err
+		}
+
+		// Most players do not have an override, so misses are cached for the full ttl
+		overrides = make([]player.Override, 0, 1)
+		if err == nil {
+			overrides = append(overrides, o)
+		}
+		r.overrides.Set(key, overrides, r.ttl, 0)
+	}
+
+	if len(overrides) == 0 {
+		return player.Override{}, player.ErrOverrideNotFound
+	}
+
+	return overrides[0], nil
+}
+
+func (r *Repository) FindOverrides(ctx context.Context) ([]player.Override, error) {
+	return r.repository.FindOverrides(ctx)
+}
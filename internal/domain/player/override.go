@@ -0,0 +1,18 @@
+package player
+
+import (
+	"time"
+
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+// Override Manually set provider for any player using the PID, taking precedence over imported players
+type Override struct {
+	PID      int
+	Provider provider.Provider
+	// Author Who set the override
+	Author string
+	// Reason Why the override was set
+	Reason  string
+	Updated time.Time
+}
@@ -3,14 +3,24 @@ package player
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 var (
 	ErrPlayerNotFound       = errors.New("player not found")
 	ErrMultiplePlayersFound = errors.New("found multiple players")
+	ErrOverrideNotFound     = errors.New("override not found")
 )
 
 type Repository interface {
 	UpsertMany(ctx context.Context, players []Player) (int, error)
 	FindByPID(ctx context.Context, pid int) (Player, error)
+	// FindAllByPID Find all players using the PID (across providers), returning an empty slice if there are none
+	FindAllByPID(ctx context.Context, pid int) ([]Player, error)
+	// FindLastImported Find the time the most recently imported player was imported (zero if there are no players)
+	FindLastImported(ctx context.Context) (time.Time, error)
+	UpsertOverride(ctx context.Context, override Override) error
+	DeleteOverride(ctx context.Context, pid int) error
+	FindOverrideByPID(ctx context.Context, pid int) (Override, error)
+	FindOverrides(ctx context.Context) ([]Override, error)
 }
@@ -0,0 +1,140 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/cetteup/playerpath/internal/domain/player"
+)
+
+const (
+	overrideTable = "player_overrides"
+
+	columnAuthor  = "author"
+	columnReason  = "reason"
+	columnUpdated = "updated"
+)
+
+func (r *Repository) UpsertOverride(ctx context.Context, o player.Override) error {
+	query := sq.
+		Insert(overrideTable).
+		Columns(
+			columnPID,
+			columnProvider,
+			columnAuthor,
+			columnReason,
+			columnUpdated,
+		).
+		Values(
+			o.PID,
+			o.Provider,
+			o.Author,
+			o.Reason,
+			o.Updated,
+		).
+		Suffix(fmt.Sprintf("ON DUPLICATE KEY UPDATE %s", strings.Join([]string{
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnProvider),
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnAuthor),
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnReason),
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnUpdated),
+		}, ", ")))
+
+	_, err := query.RunWith(r.db).ExecContext(ctx)
+	return err
+}
+
+func (r *Repository) DeleteOverride(ctx context.Context, pid int) error {
+	query := sq.
+		Delete(overrideTable).
+		Where(sq.Eq{columnPID: pid})
+
+	result, err := query.RunWith(r.db).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return player.ErrOverrideNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) FindOverrideByPID(ctx context.Context, pid int) (player.Override, error) {
+	query := selectOverrides().
+		Where(sq.Eq{columnPID: pid})
+
+	var o player.Override
+	err := query.RunWith(r.db).QueryRowContext(ctx).Scan(
+		&o.PID,
+		&o.Provider,
+		&o.Author,
+		&o.Reason,
+		&o.Updated,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return player.Override{}, player.ErrOverrideNotFound
+		}
+		return player.Override{}, err
+	}
+
+	return o, nil
+}
+
+func (r *Repository) FindOverrides(ctx context.Context) ([]player.Override, error) {
+	query := selectOverrides().
+		OrderBy(
+			fmt.Sprintf("%s ASC", columnPID),
+		)
+
+	rows, err := query.RunWith(r.db).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	overrides := make([]player.Override, 0)
+	for rows.Next() {
+		var o player.Override
+		if err = rows.Scan(
+			&o.PID,
+			&o.Provider,
+			&o.Author,
+			&o.Reason,
+			&o.Updated,
+		); err != nil {
+			return nil, err
+		}
+
+		overrides = append(overrides, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+func selectOverrides() sq.SelectBuilder {
+	return sq.
+		Select(
+			columnPID,
+			columnProvider,
+			columnAuthor,
+			columnReason,
+			columnUpdated,
+		).
+		From(overrideTable)
+}
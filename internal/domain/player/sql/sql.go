@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 
@@ -68,6 +69,23 @@ func (r *Repository) UpsertMany(ctx context.Context, players []player.Player) (i
 }
 
 func (r *Repository) FindByPID(ctx context.Context, pid int) (player.Player, error) {
+	// Load all results, as we need to ensure we only find exactly one player
+	players, err := r.FindAllByPID(ctx, pid)
+	if err != nil {
+		return player.Player{}, err
+	}
+
+	if len(players) == 0 {
+		return player.Player{}, player.ErrPlayerNotFound
+	}
+	if len(players) > 1 {
+		return player.Player{}, player.ErrMultiplePlayersFound
+	}
+
+	return players[0], nil
+}
+
+func (r *Repository) FindAllByPID(ctx context.Context, pid int) ([]player.Player, error) {
 	query := sq.
 		Select(
 			columnPID,
@@ -85,10 +103,10 @@ func (r *Repository) FindByPID(ctx context.Context, pid int) (player.Player, err
 
 	rows, err := query.RunWith(r.db).QueryContext(ctx)
 	if err != nil {
-		return player.Player{}, err
+		return nil, err
 	}
+	defer func() { _ = rows.Close() }()
 
-	// Load all results, as we need to ensure we only find exactly one player
 	players := make([]player.Player, 0)
 	for rows.Next() {
 		var p player.Player
@@ -98,22 +116,28 @@ func (r *Repository) FindByPID(ctx context.Context, pid int) (player.Player, err
 			&p.Provider,
 			&p.Imported,
 		); err != nil {
-			return player.Player{}, err
+			return nil, err
 		}
 
 		players = append(players, p)
 	}
 
 	if err = rows.Err(); err != nil {
-		return player.Player{}, err
+		return nil, err
 	}
 
-	if len(players) == 0 {
-		return player.Player{}, player.ErrPlayerNotFound
-	}
-	if len(players) > 1 {
-		return player.Player{}, player.ErrMultiplePlayersFound
+	return players, nil
+}
+
+func (r *Repository) FindLastImported(ctx context.Context) (time.Time, error) {
+	query := sq.
+		Select(fmt.Sprintf("MAX(%s)", columnImported)).
+		From(playerTable)
+
+	var imported sql.NullTime
+	if err := query.RunWith(r.db).QueryRowContext(ctx).Scan(&imported); err != nil {
+		return time.Time{}, err
 	}
 
-	return players[0], nil
+	return imported.Time, nil
 }
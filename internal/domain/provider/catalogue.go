@@ -0,0 +1,231 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// VerificationStandard Provider responds to VerifyPlayer.aspx requests in the format expected by the game server
+	VerificationStandard = "standard"
+	// VerificationBF2Hub Provider responds to VerifyPlayer.aspx requests in BF2Hub's format, which needs to be transformed
+	VerificationBF2Hub = "bf2hub"
+
+	// maxNameLength Maximum length of provider names, limited by the providers table
+	maxNameLength = 32
+)
+
+// Definition Describes a provider, along with the quirks which need to be accounted for when forwarding requests to it
+type Definition struct {
+	// ID Numeric ID used to reference the provider in the database, must never change once players have been imported
+	ID      int    `yaml:"id"`
+	Name    string `yaml:"name"`
+	BaseURL string `yaml:"url"`
+	// Registry Name of the provider in the player registry, used to import its players (not imported if empty)
+	Registry string `yaml:"registry"`
+	// GameSpyHost Provider only serves ASP requests with original gamespy.com host headers
+	GameSpyHost bool `yaml:"gamespy_host"`
+	// BFHQInfoQuery Provider only returns player info if the info query parameter matches the one used for the in-game BFHQ
+	BFHQInfoQuery bool `yaml:"bfhq_info_query"`
+	// TsdataHeader Provider uses the X-BF2Hub-TSData header to authenticate snapshots
+	TsdataHeader bool `yaml:"tsdata_header"`
+	// Verification Format of the provider's VerifyPlayer.aspx responses (standard|bf2hub, passed through as is if empty)
+	Verification string `yaml:"verification"`
+}
+
+// Defaults Built-in catalogue, to which any providers defined in the config are added
+var Defaults = []Definition{
+	{
+		ID:            int(BF2Hub),
+		Name:          "BF2Hub",
+		BaseURL:       "http://official.ranking.bf2hub.com/",
+		Registry:      "bf2hub",
+		GameSpyHost:   true,
+		BFHQInfoQuery: true,
+		TsdataHeader:  true,
+		Verification:  VerificationBF2Hub,
+	},
+	{
+		ID:           int(PlayBF2),
+		Name:         "PlayBF2",
+		BaseURL:      "http://bf2web.playbf2.ru/",
+		Registry:     "playbf2",
+		Verification: VerificationStandard,
+	},
+	{
+		ID:           int(OpenSpy),
+		Name:         "OpenSpy",
+		BaseURL:      "http://bf2web.openspy.net/",
+		Registry:     "openspy",
+		Verification: VerificationStandard,
+	},
+	{
+		ID:           int(B2BF2),
+		Name:         "B2BF2",
+		BaseURL:      "https://stats.b2bf2.net/",
+		Registry:     "b2bf2",
+		Verification: VerificationStandard,
+	},
+	{
+		ID:       int(Gameppy),
+		Name:     "Gameppy",
+		BaseURL:  "http://rank.gameppy.com/",
+		Registry: "gameppy",
+	},
+}
+
+type catalogue struct {
+	definitions []Definition
+	byID        map[Provider]Definition
+}
+
+var current atomic.Pointer[catalogue]
+
+func init() {
+	if err := Load(Defaults); err != nil {
+		panic(err)
+	}
+}
+
+// Load Replace the catalogue of known providers, e.g. with the providers defined in the config
+func Load(definitions []Definition) error {
+	c, err := newCatalogue(definitions)
+	if err != nil {
+		return err
+	}
+
+	current.Store(c)
+	return nil
+}
+
+// Validate Check whether the definitions can be loaded as the catalogue of known providers, without loading them
+func Validate(definitions []Definition) error {
+	_, err := newCatalogue(definitions)
+	return err
+}
+
+// Parse Find the provider with the given name (case-insensitive) among the definitions
+// (provider.Unknown if the name is empty)
+func Parse(definitions []Definition, name string) (Provider, error) {
+	if name == "" {
+		return Unknown, nil
+	}
+
+	for _, d := range definitions {
+		if strings.EqualFold(name, d.Name) {
+			return d.Provider(), nil
+		}
+	}
+
+	return Unknown, fmt.Errorf("invalid provider: %s", name)
+}
+
+func newCatalogue(definitions []Definition) (*catalogue, error) {
+	c := &catalogue{
+		definitions: slices.Clone(definitions),
+		byID:        make(map[Provider]Definition, len(definitions)),
+	}
+	names := make(map[string]struct{}, len(definitions))
+	for _, d := range definitions {
+		if err := d.validate(); err != nil {
+			return nil, err
+		}
+
+		if _, exists := c.byID[d.Provider()]; exists {
+			return nil, fmt.Errorf("duplicate provider id: %d", d.ID)
+		}
+		c.byID[d.Provider()] = d
+
+		name := strings.ToLower(d.Name)
+		if _, exists := names[name]; exists {
+			return nil, fmt.Errorf("duplicate provider name: %s", d.Name)
+		}
+		names[name] = struct{}{}
+	}
+
+	return c, nil
+}
+
+// Merge Combine the given definitions, with later definitions replacing earlier ones using the same ID
+func Merge(definitions ...[]Definition) []Definition {
+	var merged []Definition
+	for _, ds := range definitions {
+		for _, d := range ds {
+			i := slices.IndexFunc(merged, func(m Definition) bool {
+				return m.ID == d.ID
+			})
+			if i != -1 {
+				merged[i] = d
+			} else {
+				merged = append(merged, d)
+			}
+		}
+	}
+	return merged
+}
+
+// Lookup Find the definition of the given provider
+func Lookup(p Provider) (Definition, bool) {
+	d, ok := current.Load().byID[p]
+	return d, ok
+}
+
+// All Get the definitions of all known providers
+func All() []Definition {
+	return slices.Clone(current.Load().definitions)
+}
+
+// Importable Get all providers whose players can be imported from the player registry
+func Importable() []Provider {
+	var providers []Provider
+	for _, d := range current.Load().definitions {
+		if d.Registry != "" {
+			providers = append(providers, d.Provider())
+		}
+	}
+	return providers
+}
+
+// GetRegistryName Get the name of the given provider in the player registry
+func GetRegistryName(p Provider) string {
+	d, _ := Lookup(p)
+	return d.Registry
+}
+
+// Provider Get the provider described by the definition
+func (d Definition) Provider() Provider {
+	return Provider(d.ID)
+}
+
+func (d Definition) validate() error {
+	if d.Provider() <= Unknown {
+		return fmt.Errorf("invalid provider id: %d", d.ID)
+	}
+
+	if d.Name == "" {
+		return errors.New("provider name must not be empty")
+	}
+	if len(d.Name) > maxNameLength || strings.EqualFold(d.Name, Unknown.String()) {
+		return fmt.Errorf("invalid provider name: %s", d.Name)
+	}
+
+	u, err := url.Parse(d.BaseURL)
+	if err != nil {
+		return fmt.Errorf("invalid url for provider %s: %w", d.Name, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("invalid url for provider %s: %s", d.Name, d.BaseURL)
+	}
+
+	switch d.Verification {
+	case "", VerificationStandard, VerificationBF2Hub:
+	default:
+		return fmt.Errorf("invalid verification format for provider %s: %s", d.Name, d.Verification)
+	}
+
+	return nil
+}
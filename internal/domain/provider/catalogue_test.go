@@ -0,0 +1,152 @@
+package provider_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name             string
+		givenDefinitions []provider.Definition
+		wantErrContains  string
+	}{
+		{
+			name:             "loads valid catalogue",
+			givenDefinitions: provider.Defaults,
+		},
+		{
+			name: "errors for duplicate id",
+			givenDefinitions: []provider.Definition{
+				{ID: 1, Name: "first", BaseURL: "http://first/"},
+				{ID: 1, Name: "second", BaseURL: "http://second/"},
+			},
+			wantErrContains: "duplicate provider id",
+		},
+		{
+			name: "errors for duplicate name",
+			givenDefinitions: []provider.Definition{
+				{ID: 1, Name: "same", BaseURL: "http://first/"},
+				{ID: 2, Name: "SAME", BaseURL: "http://second/"},
+			},
+			wantErrContains: "duplicate provider name",
+		},
+		{
+			name: "errors for reserved id",
+			givenDefinitions: []provider.Definition{
+				{ID: 0, Name: "zero", BaseURL: "http://zero/"},
+			},
+			wantErrContains: "invalid provider id",
+		},
+		{
+			name: "errors for invalid url",
+			givenDefinitions: []provider.Definition{
+				{ID: 1, Name: "nourl", BaseURL: "official.ranking.bf2hub.com"},
+			},
+			wantErrContains: "invalid url",
+		},
+		{
+			name: "errors for invalid verification format",
+			givenDefinitions: []provider.Definition{
+				{ID: 1, Name: "custom", BaseURL: "http://custom/", Verification: "gamespy"},
+			},
+			wantErrContains: "invalid verification format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				require.NoError(t, provider.Load(provider.Defaults))
+			})
+
+			// WHEN
+			err := provider.Load(tt.givenDefinitions)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				assert.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCatalogue(t *testing.T) {
+	t.Cleanup(func() {
+		require.NoError(t, provider.Load(provider.Defaults))
+	})
+
+	// GIVEN
+	err := provider.Load(append(provider.Defaults, provider.Definition{
+		ID:           42,
+		Name:         "League",
+		BaseURL:      "https://stats.example.com/",
+		GameSpyHost:  true,
+		Verification: provider.VerificationStandard,
+	}))
+	require.NoError(t, err)
+
+	// WHEN
+	var pv provider.Provider
+	err = pv.UnmarshalText([]byte("league"))
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, provider.Provider(42), pv)
+	assert.Equal(t, "League", pv.String())
+	assert.Equal(t, "https://stats.example.com/", provider.GetBaseURL(pv))
+	assert.True(t, provider.RequiresGameSpyHost(pv))
+	assert.False(t, provider.RequiresBFHQInfoQuery(pv))
+	assert.True(t, provider.SupportsStandardPlayerVerification(pv))
+	assert.NotContains(t, provider.Importable(), pv)
+	assert.Equal(t, "Provider(43)", provider.Provider(43).String())
+}
+
+func TestMerge(t *testing.T) {
+	// GIVEN
+	builtin := []provider.Definition{
+		{ID: 1, Name: "first", BaseURL: "http://first/"},
+		{ID: 2, Name: "second", BaseURL: "http://second/"},
+	}
+	configured := []provider.Definition{
+		{ID: 2, Name: "second", BaseURL: "http://staging.second/"},
+		{ID: 3, Name: "custom", BaseURL: "http://custom/"},
+	}
+
+	// WHEN
+	merged := provider.Merge(builtin, configured)
+
+	// THEN
+	assert.Equal(t, []provider.Definition{
+		{ID: 1, Name: "first", BaseURL: "http://first/"},
+		{ID: 2, Name: "second", BaseURL: "http://staging.second/"},
+		{ID: 3, Name: "custom", BaseURL: "http://custom/"},
+	}, merged)
+}
+
+func TestParse(t *testing.T) {
+	// GIVEN
+	definitions := append(provider.Defaults, provider.Definition{
+		ID:      42,
+		Name:    "League",
+		BaseURL: "https://stats.example.com/",
+	})
+
+	// WHEN
+	pv, err := provider.Parse(definitions, "league")
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, provider.Provider(42), pv)
+	// Parsing must not affect the catalogue of known providers
+	_, ok := provider.Lookup(pv)
+	assert.False(t, ok)
+	_, err = provider.Parse(provider.Defaults, "league")
+	assert.ErrorContains(t, err, "invalid provider")
+}
@@ -1,13 +1,12 @@
-//go:generate go tool stringer -type=Provider
 package provider
 
 import (
-	"fmt"
-	"strings"
+	"strconv"
 )
 
 type Provider int
 
+// Providers shipped with playerpath, which remain available by ID even if the catalogue is replaced
 const (
 	Unknown Provider = 0
 	BF2Hub  Provider = 1
@@ -15,36 +14,29 @@ const (
 	OpenSpy Provider = 3
 	B2BF2   Provider = 4
 	Gameppy Provider = 5
-
-	baseURLBF2Hub  = "http://official.ranking.bf2hub.com/"
-	baseURLPlayBF2 = "http://bf2web.playbf2.ru/"
-	baseURLOpenSpy = "http://bf2web.openspy.net/"
-	baseURLB2BF2   = "https://stats.b2bf2.net/"
-	baseURLGameppy = "http://rank.gameppy.com/"
 )
 
-//goland:noinspection GoMixedReceiverTypes
-func (p *Provider) UnmarshalText(text []byte) error {
-	if len(text) == 0 {
-		*p = Unknown
-		return nil
+func (p Provider) String() string {
+	if p == Unknown {
+		return "Unknown"
+	}
+
+	d, ok := Lookup(p)
+	if !ok {
+		return "Provider(" + strconv.Itoa(int(p)) + ")"
 	}
 
-	s := string(text)
-	if strings.EqualFold(s, BF2Hub.String()) {
-		*p = BF2Hub
-	} else if strings.EqualFold(s, PlayBF2.String()) {
-		*p = PlayBF2
-	} else if strings.EqualFold(s, OpenSpy.String()) {
-		*p = OpenSpy
-	} else if strings.EqualFold(s, B2BF2.String()) {
-		*p = B2BF2
-	} else if strings.EqualFold(s, Gameppy.String()) {
-		*p = Gameppy
-	} else {
-		return fmt.Errorf("invalid provider: %s", s)
+	return d.Name
+}
+
+//goland:noinspection GoMixedReceiverTypes
+func (p *Provider) UnmarshalText(text []byte) error {
+	pv, err := Parse(All(), string(text))
+	if err != nil {
+		return err
 	}
 
+	*p = pv
 	return nil
 }
 
@@ -54,47 +46,35 @@ func (p Provider) MarshalText() (text []byte, err error) {
 }
 
 func GetBaseURL(p Provider) string {
-	switch p {
-	case BF2Hub:
-		return baseURLBF2Hub
-	case PlayBF2:
-		return baseURLPlayBF2
-	case OpenSpy:
-		return baseURLOpenSpy
-	case B2BF2:
-		return baseURLB2BF2
-	case Gameppy:
-		return baseURLGameppy
-	default:
+	d, ok := Lookup(p)
+	if !ok {
 		return "http://unknown"
 	}
+
+	return d.BaseURL
 }
 
 func RequiresGameSpyHost(p Provider) bool {
-	switch p {
-	// BF2Hub only serves ASP requests with original gamespy.com host headers
-	case BF2Hub:
-		return true
-	default:
-		return false
-	}
+	d, ok := Lookup(p)
+	return ok && d.GameSpyHost
 }
 
 func RequiresBFHQInfoQuery(p Provider) bool {
-	switch p {
-	// BF2Hub only returns player info if the info query parameter matches the one used for the in-game BFHQ
-	case BF2Hub:
-		return true
-	default:
-		return false
-	}
+	d, ok := Lookup(p)
+	return ok && d.BFHQInfoQuery
 }
 
 func SupportsStandardPlayerVerification(p Provider) bool {
-	switch p {
-	case PlayBF2, OpenSpy, B2BF2:
-		return true
-	default:
-		return false
-	}
+	d, ok := Lookup(p)
+	return ok && d.Verification == VerificationStandard
+}
+
+func UsesBF2HubPlayerVerification(p Provider) bool {
+	d, ok := Lookup(p)
+	return ok && d.Verification == VerificationBF2Hub
+}
+
+func SupportsTsdataHeader(p Provider) bool {
+	d, ok := Lookup(p)
+	return ok && d.TsdataHeader
 }
@@ -0,0 +1,10 @@
+package provider
+
+import (
+	"context"
+)
+
+type Repository interface {
+	// Sync Insert or update the given providers, ensuring players etc. can reference them
+	Sync(ctx context.Context, definitions []Definition) error
+}
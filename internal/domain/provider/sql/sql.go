@@ -0,0 +1,55 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+const (
+	providerTable = "providers"
+
+	columnID   = "id"
+	columnName = "name"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Sync(ctx context.Context, definitions []provider.Definition) error {
+	if len(definitions) == 0 {
+		return nil
+	}
+
+	query := sq.
+		Insert(providerTable).
+		Columns(
+			columnID,
+			columnName,
+		).
+		Suffix(fmt.Sprintf("ON DUPLICATE KEY UPDATE %s", strings.Join([]string{
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnName),
+		}, ", ")))
+
+	for _, d := range definitions {
+		query = query.Values(
+			d.ID,
+			strings.ToLower(d.Name),
+		)
+	}
+
+	_, err := query.RunWith(r.db).ExecContext(ctx)
+	return err
+}
@@ -0,0 +1,17 @@
+package response
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+var (
+	ErrResponseNotFound = errors.New("response not found")
+)
+
+type Repository interface {
+	Upsert(ctx context.Context, response Response) error
+	Find(ctx context.Context, pid int, pv provider.Provider, endpoint string, queryHash string) (Response, error)
+}
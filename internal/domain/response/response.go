@@ -0,0 +1,18 @@
+package response
+
+import (
+	"time"
+
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+// Response Last successful ASP response from a provider for a player and endpoint
+type Response struct {
+	PID      int
+	Provider provider.Provider
+	Endpoint string
+	// QueryHash Digest of the normalized request query, since responses may depend on it (e.g. getplayerinfo.aspx's info)
+	QueryHash string
+	Body      []byte
+	Updated   time.Time
+}
@@ -0,0 +1,106 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/cetteup/playerpath/internal/domain/provider"
+	"github.com/cetteup/playerpath/internal/domain/response"
+)
+
+const (
+	responseTable = "responses"
+
+	columnPID       = "pid"
+	columnProvider  = "provider"
+	columnEndpoint  = "endpoint"
+	columnQueryHash = "query_hash"
+	columnBody      = "body"
+	columnUpdated   = "updated"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Upsert(ctx context.Context, res response.Response) error {
+	query := sq.
+		Insert(responseTable).
+		Columns(
+			columnPID,
+			columnProvider,
+			columnEndpoint,
+			columnQueryHash,
+			columnBody,
+			columnUpdated,
+		).
+		Values(
+			res.PID,
+			res.Provider,
+			res.Endpoint,
+			res.QueryHash,
+			res.Body,
+			res.Updated,
+		).
+		Suffix(fmt.Sprintf("ON DUPLICATE KEY UPDATE %s", strings.Join([]string{
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnBody),
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnUpdated),
+		}, ", ")))
+
+	_, err := query.RunWith(r.db).ExecContext(ctx)
+	return err
+}
+
+func (r *Repository) Find(
+	ctx context.Context,
+	pid int,
+	pv provider.Provider,
+	endpoint string,
+	queryHash string,
+) (response.Response, error) {
+	query := sq.
+		Select(
+			columnPID,
+			columnProvider,
+			columnEndpoint,
+			columnQueryHash,
+			columnBody,
+			columnUpdated,
+		).
+		From(responseTable).
+		Where(sq.And{
+			sq.Eq{columnPID: pid},
+			sq.Eq{columnProvider: pv},
+			sq.Eq{columnEndpoint: endpoint},
+			sq.Eq{columnQueryHash: queryHash},
+		})
+
+	var res response.Response
+	err := query.RunWith(r.db).QueryRowContext(ctx).Scan(
+		&res.PID,
+		&res.Provider,
+		&res.Endpoint,
+		&res.QueryHash,
+		&res.Body,
+		&res.Updated,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return response.Response{}, response.ErrResponseNotFound
+		}
+		return response.Response{}, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,18 @@
+package verification
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrVerificationNotFound = errors.New("verification not found")
+)
+
+type Repository interface {
+	Upsert(ctx context.Context, verification Verification) error
+	// FindLatestByPID Find the most recent verification of any player using the PID
+	FindLatestByPID(ctx context.Context, pid int) (Verification, error)
+	// FindLatestByPIDAndServer Find the most recent verification of any player using the PID on the given server
+	FindLatestByPIDAndServer(ctx context.Context, pid int, serverIP string) (Verification, error)
+}
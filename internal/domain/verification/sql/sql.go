@@ -0,0 +1,106 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/cetteup/playerpath/internal/domain/verification"
+)
+
+const (
+	verificationTable = "verifications"
+
+	columnPID      = "pid"
+	columnProvider = "provider"
+	columnNick     = "nick"
+	columnServer   = "server"
+	columnVerified = "verified"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Upsert(ctx context.Context, v verification.Verification) error {
+	query := sq.
+		Insert(verificationTable).
+		Columns(
+			columnPID,
+			columnProvider,
+			columnNick,
+			columnServer,
+			columnVerified,
+		).
+		Values(
+			v.PID,
+			v.Provider,
+			v.Nick,
+			v.ServerIP,
+			v.Verified,
+		).
+		// Server is part of the primary key, keeping a separate verification per server
+		Suffix(fmt.Sprintf("ON DUPLICATE KEY UPDATE %s", strings.Join([]string{
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnNick),
+			fmt.Sprintf("%[1]s = VALUES(%[1]s)", columnVerified),
+		}, ", ")))
+
+	_, err := query.RunWith(r.db).ExecContext(ctx)
+	return err
+}
+
+func (r *Repository) FindLatestByPID(ctx context.Context, pid int) (verification.Verification, error) {
+	return r.findLatest(ctx, sq.Eq{columnPID: pid})
+}
+
+func (r *Repository) FindLatestByPIDAndServer(
+	ctx context.Context,
+	pid int,
+	serverIP string,
+) (verification.Verification, error) {
+	return r.findLatest(ctx, sq.Eq{columnPID: pid}, sq.Eq{columnServer: serverIP})
+}
+
+func (r *Repository) findLatest(ctx context.Context, conditions ...sq.Sqlizer) (verification.Verification, error) {
+	query := sq.
+		Select(
+			columnPID,
+			columnProvider,
+			columnNick,
+			columnServer,
+			columnVerified,
+		).
+		From(verificationTable).
+		Where(sq.And(conditions)).
+		OrderBy(
+			fmt.Sprintf("%s DESC", columnVerified),
+		).
+		Limit(1)
+
+	var v verification.Verification
+	err := query.RunWith(r.db).QueryRowContext(ctx).Scan(
+		&v.PID,
+		&v.Provider,
+		&v.Nick,
+		&v.ServerIP,
+		&v.Verified,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return verification.Verification{}, verification.ErrVerificationNotFound
+		}
+		return verification.Verification{}, err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,16 @@
+package verification
+
+import (
+	"time"
+
+	"github.com/cetteup/playerpath/internal/domain/provider"
+)
+
+// Verification Successful verification of a player by a provider
+type Verification struct {
+	PID      int
+	Nick     string
+	Provider provider.Provider
+	ServerIP string
+	Verified time.Time
+}
@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+type Freshness int
+
+const (
+	Miss  Freshness = 0
+	Fresh Freshness = 1
+	Stale Freshness = 2
+)
+
+var (
+	Now = time.Now // mockable for tests
+)
+
+type entry[V any] struct {
+	key          string
+	value        V
+	expires      time.Time
+	staleExpires time.Time
+	// index Position in the expiry heap
+	index int
+}
+
+// expiryHeap Min-heap of entries ordered by the end of their stale period, allowing eviction without a full scan
+type expiryHeap[V any] []*entry[V]
+
+func (h expiryHeap[V]) Len() int { return len(h) }
+
+func (h expiryHeap[V]) Less(i, j int) bool { return h[i].staleExpires.Before(h[j].staleExpires) }
+
+func (h expiryHeap[V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap[V]) Push(x any) {
+	e := x.(*entry[V])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap[V]) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
+
+// Cache In-memory cache whose entries are fresh for their TTL and can be served stale (while being revalidated)
+// for an additional stale period. Entries past their stale period are treated as misses and evicted over time.
+type Cache[V any] struct {
+	maxEntries int
+
+	mu           sync.Mutex
+	entries      map[string]*entry[V]
+	expiry       expiryHeap[V]
+	revalidating map[string]struct{}
+}
+
+func New[V any](maxEntries int) *Cache[V] {
+	return &Cache[V]{
+		maxEntries:   maxEntries,
+		entries:      make(map[string]*entry[V]),
+		revalidating: make(map[string]struct{}),
+	}
+}
+
+func (c *Cache[V]) Get(key string) (V, Freshness) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, Miss
+	}
+
+	now := Now()
+	if now.Before(e.expires) {
+		return e.value, Fresh
+	}
+	if now.Before(e.staleExpires) {
+		return e.value, Stale
+	}
+
+	c.remove(e)
+	var zero V
+	return zero, Miss
+}
+
+func (c *Cache[V]) Set(key string, value V, ttl time.Duration, stale time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := Now()
+	if e, ok := c.entries[key]; ok {
+		e.value = value
+		e.expires = now.Add(ttl)
+		e.staleExpires = now.Add(ttl + stale)
+		heap.Fix(&c.expiry, e.index)
+		return
+	}
+
+	if len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+
+	e := &entry[V]{
+		key:          key,
+		value:        value,
+		expires:      now.Add(ttl),
+		staleExpires: now.Add(ttl + stale),
+	}
+	c.entries[key] = e
+	heap.Push(&c.expiry, e)
+}
+
+func (c *Cache[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+}
+
+// Clear Remove all entries
+func (c *Cache[V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*entry[V])
+	c.expiry = nil
+}
+
+// Revalidate Mark the key as being revalidated, returning false if it is already being revalidated.
+// Once revalidation is complete, Done must be called to allow future revalidation.
+func (c *Cache[V]) Revalidate(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.revalidating[key]; ok {
+		return false
+	}
+
+	c.revalidating[key] = struct{}{}
+	return true
+}
+
+func (c *Cache[V]) Done(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.revalidating, key)
+}
+
+func (c *Cache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// evict Make room for a new entry by removing all entries past their stale period,
+// falling back to removing the entry closest to expiring
+func (c *Cache[V]) evict(now time.Time) {
+	for len(c.expiry) > 0 && !now.Before(c.expiry[0].staleExpires) {
+		c.remove(c.expiry[0])
+	}
+
+	if len(c.entries) >= c.maxEntries && len(c.expiry) > 0 {
+		c.remove(c.expiry[0])
+	}
+}
+
+func (c *Cache[V]) remove(e *entry[V]) {
+	heap.Remove(&c.expiry, e.index)
+	delete(c.entries, e.key)
+}
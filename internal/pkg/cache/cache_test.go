@@ -0,0 +1,159 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cetteup/playerpath/internal/pkg/cache"
+)
+
+func TestCache_Get(t *testing.T) {
+	tests := []struct {
+		name          string
+		advance       time.Duration
+		wantValue     string
+		wantFreshness cache.Freshness
+	}{
+		{
+			name:          "returns fresh value within ttl",
+			advance:       59 * time.Second,
+			wantValue:     "value",
+			wantFreshness: cache.Fresh,
+		},
+		{
+			name:          "returns stale value after ttl within stale period",
+			advance:       time.Minute,
+			wantValue:     "value",
+			wantFreshness: cache.Stale,
+		},
+		{
+			name:          "returns miss after stale period",
+			advance:       6 * time.Minute,
+			wantValue:     "",
+			wantFreshness: cache.Miss,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			now := time.Date(2026, 2, 17, 23, 0, 0, 0, time.UTC)
+			cache.Now = func() time.Time { return now }
+			t.Cleanup(func() { cache.Now = time.Now })
+
+			c := cache.New[string](10)
+			c.Set("key", "value", time.Minute, 5*time.Minute)
+
+			// WHEN
+			now = now.Add(tt.advance)
+			value, freshness := c.Get("key")
+
+			// THEN
+			assert.Equal(t, tt.wantValue, value)
+			assert.Equal(t, tt.wantFreshness, freshness)
+		})
+	}
+}
+
+func TestCache_Set(t *testing.T) {
+	t.Run("evicts entry closest to expiring if full", func(t *testing.T) {
+		// GIVEN
+		c := cache.New[string](2)
+		c.Set("a", "a", time.Minute, 0)
+		c.Set("b", "b", 2*time.Minute, 0)
+
+		// WHEN
+		c.Set("c", "c", time.Minute, 0)
+
+		// THEN
+		assert.Equal(t, 2, c.Len())
+		_, freshness := c.Get("a")
+		assert.Equal(t, cache.Miss, freshness)
+		_, freshness = c.Get("b")
+		assert.Equal(t, cache.Fresh, freshness)
+	})
+
+	t.Run("evicts entries past their stale period if full", func(t *testing.T) {
+		// GIVEN
+		now := time.Date(2026, 2, 17, 23, 0, 0, 0, time.UTC)
+		cache.Now = func() time.Time { return now }
+		t.Cleanup(func() { cache.Now = time.Now })
+		c := cache.New[string](3)
+		c.Set("a", "a", time.Minute, 0)
+		c.Set("b", "b", time.Minute, 0)
+		c.Set("c", "c", time.Hour, 0)
+		now = now.Add(2 * time.Minute)
+
+		// WHEN
+		c.Set("d", "d", time.Minute, 0)
+
+		// THEN
+		assert.Equal(t, 2, c.Len())
+		_, freshness := c.Get("c")
+		assert.Equal(t, cache.Fresh, freshness)
+		_, freshness = c.Get("d")
+		assert.Equal(t, cache.Fresh, freshness)
+	})
+
+	t.Run("extends expiry of overwritten entry", func(t *testing.T) {
+		// GIVEN
+		c := cache.New[string](2)
+		c.Set("a", "a", time.Minute, 0)
+		c.Set("b", "b", 2*time.Minute, 0)
+		c.Set("a", "a", time.Hour, 0)
+
+		// WHEN
+		c.Set("c", "c", time.Hour, 0)
+
+		// THEN
+		_, freshness := c.Get("a")
+		assert.Equal(t, cache.Fresh, freshness)
+		_, freshness = c.Get("b")
+		assert.Equal(t, cache.Miss, freshness)
+	})
+
+	t.Run("overwrites existing entry if full", func(t *testing.T) {
+		// GIVEN
+		c := cache.New[string](2)
+		c.Set("a", "a", time.Minute, 0)
+		c.Set("b", "b", time.Minute, 0)
+
+		// WHEN
+		c.Set("a", "updated", time.Minute, 0)
+
+		// THEN
+		assert.Equal(t, 2, c.Len())
+		value, _ := c.Get("a")
+		assert.Equal(t, "updated", value)
+	})
+}
+
+func TestCache_Clear(t *testing.T) {
+	// GIVEN
+	c := cache.New[string](2)
+	c.Set("a", "a", time.Minute, 0)
+	c.Set("b", "b", time.Minute, 0)
+
+	// WHEN
+	c.Clear()
+
+	// THEN
+	assert.Equal(t, 0, c.Len())
+	_, freshness := c.Get("a")
+	assert.Equal(t, cache.Miss, freshness)
+	c.Set("c", "c", time.Minute, 0)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestCache_Revalidate(t *testing.T) {
+	// GIVEN
+	c := cache.New[string](10)
+
+	// WHEN/THEN
+	assert.True(t, c.Revalidate("key"))
+	assert.False(t, c.Revalidate("key"))
+	c.Done("key")
+	assert.True(t, c.Revalidate("key"))
+}
@@ -32,3 +32,15 @@ func Connect(host, dbname, user, passwd string) *sql.DB {
 
 	return db
 }
+
+// Truncate Shorten the string to at most n characters (the unit of utf8mb4 column limits), never splitting a character
+func Truncate(s string, n int) string {
+	count := 0
+	for i := range s {
+		if count == n {
+			return s[:i]
+		}
+		count++
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package sqlutil_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cetteup/playerpath/internal/sqlutil"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		n    int
+		want string
+	}{
+		{
+			name: "keeps string within limit",
+			s:    "timeout",
+			n:    7,
+			want: "timeout",
+		},
+		{
+			name: "truncates string exceeding limit",
+			s:    "timeout",
+			n:    4,
+			want: "time",
+		},
+		{
+			name: "counts multi-byte characters as one",
+			s:    "ошибка сети",
+			n:    6,
+			want: "ошибка",
+		},
+		{
+			name: "truncates empty string",
+			s:    "",
+			n:    4,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			truncated := sqlutil.Truncate(tt.s, tt.n)
+
+			// THEN
+			assert.Equal(t, tt.want, truncated)
+		})
+	}
+}
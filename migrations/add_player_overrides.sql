@@ -0,0 +1,12 @@
+-- Manual per-player provider overrides
+CREATE TABLE IF NOT EXISTS `player_overrides`
+(
+    `pid`      int(11) NOT NULL,
+    `provider` int(1) NOT NULL,
+    `author`   varchar(50)  NOT NULL,
+    `reason`   varchar(255) NOT NULL,
+    `updated`  datetime     NOT NULL,
+    PRIMARY KEY (`pid`),
+    KEY        `player_overrides_providers_FK` (`provider`),
+    CONSTRAINT `player_overrides_providers_FK` FOREIGN KEY (`provider`) REFERENCES `providers` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
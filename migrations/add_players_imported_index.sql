@@ -0,0 +1,2 @@
+-- Index used to check for newly imported players without scanning the entire table
+CREATE INDEX IF NOT EXISTS `players_imported` ON `players` (`imported`);
@@ -0,0 +1,13 @@
+-- Last good responses, served for fallback endpoints if a provider fails to respond
+CREATE TABLE IF NOT EXISTS `responses`
+(
+    `pid`        int(11) NOT NULL,
+    `provider`   int(1) NOT NULL,
+    `endpoint`   varchar(50) NOT NULL,
+    `query_hash` char(64)    NOT NULL,
+    `body`       mediumblob  NOT NULL,
+    `updated`    datetime    NOT NULL,
+    PRIMARY KEY (`pid`, `provider`, `endpoint`, `query_hash`),
+    KEY        `responses_providers_FK` (`provider`),
+    CONSTRAINT `responses_providers_FK` FOREIGN KEY (`provider`) REFERENCES `providers` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,32 @@
+-- Snapshot archive and per-provider delivery status
+CREATE TABLE IF NOT EXISTS `snapshots`
+(
+    `id`       int(11) NOT NULL AUTO_INCREMENT,
+    `server`   varchar(45) NOT NULL,
+    `header`   text        NOT NULL,
+    `data`     mediumblob  NOT NULL,
+    `received` datetime    NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY        `snapshots_received` (`received`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `snapshot_deliveries`
+(
+    `snapshot`     int(11) NOT NULL,
+    `provider`     int(1) NOT NULL,
+    `status`       int(1) NOT NULL,
+    `code`         int(3) NOT NULL,
+    `error`        varchar(255) NOT NULL,
+    `attempts`     int(11) NOT NULL,
+    `next_attempt` datetime     NOT NULL,
+    `updated`      datetime     NOT NULL,
+    PRIMARY KEY (`snapshot`, `provider`),
+    KEY            `snapshot_deliveries_providers_FK` (`provider`),
+    KEY            `snapshot_deliveries_status_IDX` (`provider`, `status`),
+    KEY            `snapshot_deliveries_next_attempt_IDX` (`status`, `next_attempt`),
+    CONSTRAINT `snapshot_deliveries_snapshots_FK` FOREIGN KEY (`snapshot`) REFERENCES `snapshots` (`id`) ON DELETE CASCADE,
+    CONSTRAINT `snapshot_deliveries_providers_FK` FOREIGN KEY (`provider`) REFERENCES `providers` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Index used to prune archived snapshots (for installations which already have the snapshots table)
+CREATE INDEX IF NOT EXISTS `snapshots_received` ON `snapshots` (`received`);
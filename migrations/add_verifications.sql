@@ -0,0 +1,14 @@
+-- Verification history
+CREATE TABLE IF NOT EXISTS `verifications`
+(
+    `pid`      int(11) NOT NULL,
+    `provider` int(1) NOT NULL,
+    `nick`     varchar(50) NOT NULL,
+    `server`   varchar(45) NOT NULL,
+    `verified` datetime    NOT NULL,
+    PRIMARY KEY (`pid`, `provider`, `server`),
+    KEY        `verifications_pid_verified` (`pid`, `verified`),
+    KEY        `verifications_pid_server_verified` (`pid`, `server`, `verified`),
+    KEY        `verifications_providers_FK` (`provider`),
+    CONSTRAINT `verifications_providers_FK` FOREIGN KEY (`provider`) REFERENCES `providers` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,2 @@
+-- Provider names of up to 32 characters, as allowed for providers defined in the config
+ALTER TABLE `providers` MODIFY `name` varchar(32) NOT NULL;
@@ -1,6 +0,0 @@
-INSERT INTO `providers` (`id`, `name`)
-VALUES (1, 'bf2hub'),
-       (2, 'playbf2'),
-       (3, 'openspy'),
-       (4, 'b2bf2'),
-       (5, 'gameppy');
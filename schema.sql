@@ -1,7 +1,7 @@
 CREATE TABLE `providers`
 (
     `id`   int(1) NOT NULL,
-    `name` varchar(10) NOT NULL,
+    `name` varchar(32) NOT NULL,
     PRIMARY KEY (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
@@ -12,6 +12,75 @@ CREATE TABLE `players`
     `provider` int(1) NOT NULL,
     `imported` datetime    NOT NULL,
     PRIMARY KEY (`pid`, `provider`),
+    KEY        `players_imported` (`imported`),
     KEY        `players_providers_FK` (`provider`),
     CONSTRAINT `players_providers_FK` FOREIGN KEY (`provider`) REFERENCES `providers` (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `snapshots`
+(
+    `id`       int(11) NOT NULL AUTO_INCREMENT,
+    `server`   varchar(45) NOT NULL,
+    `header`   text        NOT NULL,
+    `data`     mediumblob  NOT NULL,
+    `received` datetime    NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY        `snapshots_received` (`received`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `snapshot_deliveries`
+(
+    `snapshot`     int(11) NOT NULL,
+    `provider`     int(1) NOT NULL,
+    `status`       int(1) NOT NULL,
+    `code`         int(3) NOT NULL,
+    `error`        varchar(255) NOT NULL,
+    `attempts`     int(11) NOT NULL,
+    `next_attempt` datetime     NOT NULL,
+    `updated`      datetime     NOT NULL,
+    PRIMARY KEY (`snapshot`, `provider`),
+    KEY            `snapshot_deliveries_providers_FK` (`provider`),
+    KEY            `snapshot_deliveries_status_IDX` (`provider`, `status`),
+    KEY            `snapshot_deliveries_next_attempt_IDX` (`status`, `next_attempt`),
+    CONSTRAINT `snapshot_deliveries_snapshots_FK` FOREIGN KEY (`snapshot`) REFERENCES `snapshots` (`id`) ON DELETE CASCADE,
+    CONSTRAINT `snapshot_deliveries_providers_FK` FOREIGN KEY (`provider`) REFERENCES `providers` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `responses`
+(
+    `pid`        int(11) NOT NULL,
+    `provider`   int(1) NOT NULL,
+    `endpoint`   varchar(50) NOT NULL,
+    `query_hash` char(64)    NOT NULL,
+    `body`       mediumblob  NOT NULL,
+    `updated`    datetime    NOT NULL,
+    PRIMARY KEY (`pid`, `provider`, `endpoint`, `query_hash`),
+    KEY        `responses_providers_FK` (`provider`),
+    CONSTRAINT `responses_providers_FK` FOREIGN KEY (`provider`) REFERENCES `providers` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `verifications`
+(
+    `pid`      int(11) NOT NULL,
+    `provider` int(1) NOT NULL,
+    `nick`     varchar(50) NOT NULL,
+    `server`   varchar(45) NOT NULL,
+    `verified` datetime    NOT NULL,
+    PRIMARY KEY (`pid`, `provider`, `server`),
+    KEY        `verifications_pid_verified` (`pid`, `verified`),
+    KEY        `verifications_pid_server_verified` (`pid`, `server`, `verified`),
+    KEY        `verifications_providers_FK` (`provider`),
+    CONSTRAINT `verifications_providers_FK` FOREIGN KEY (`provider`) REFERENCES `providers` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `player_overrides`
+(
+    `pid`      int(11) NOT NULL,
+    `provider` int(1) NOT NULL,
+    `author`   varchar(50)  NOT NULL,
+    `reason`   varchar(255) NOT NULL,
+    `updated`  datetime     NOT NULL,
+    PRIMARY KEY (`pid`),
+    KEY        `player_overrides_providers_FK` (`provider`),
+    CONSTRAINT `player_overrides_providers_FK` FOREIGN KEY (`provider`) REFERENCES `providers` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;